@@ -23,6 +23,8 @@ func main() {
 	e.GET("/games/:id", server.GameDisplayHandler)
 	e.GET("/games/:id/history/:offset", server.GameHistoryHandler)
 	e.GET("/games/:id/board", server.GameBoardHandler)
+	e.GET("/games/:id/analysis", server.GameAnalysisHandler)
+	e.GET("/games/:id/export", server.GameExportHandler)
 	e.GET("/gamelist", server.GameListHandler)
 	e.GET("/livegamelist", server.LiveGameListHandler)
 	e.GET("/liveboard/:id", server.GameHandler)
@@ -37,5 +39,21 @@ func main() {
 	})
 	e.POST("/newgame", server.NewGameHandler)
 	e.POST("/move", server.PlayerMoveHandler)
+	e.POST("/games/import", server.GameImportHandler)
+	e.POST("/games/:id/resign", server.ResignHandler)
+	e.POST("/games/:id/draw", server.OfferDrawHandler)
+	e.POST("/games/:id/draw/accept", server.AcceptDrawHandler)
+	e.POST("/games/:id/draw/decline", server.DeclineDrawHandler)
+	e.POST("/games/:id/rematch", server.RematchHandler)
+	e.GET("/games/:id/rematch", server.RematchRedirectHandler)
+	e.POST("/games/:id/fork/:ply", server.ForkHandler)
+	e.GET("/setup", server.SetupHandler)
+	e.POST("/setup/check", server.SetupCheckHandler)
+	e.POST("/setup/analysis", server.SetupAnalysisHandler)
+	e.POST("/setup/game", server.SetupGameHandler)
+	e.GET("/matches/:id", server.MatchDisplayHandler)
+	e.POST("/games/:id/takeback", server.TakebackHandler)
+	e.POST("/games/:id/takeback/accept", server.AcceptTakebackHandler)
+	e.POST("/games/:id/takeback/decline", server.DeclineTakebackHandler)
 	e.Logger.Fatal(e.Start(":42069"))
 }
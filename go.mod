@@ -3,13 +3,13 @@ module jay/tictactoe
 go 1.22.6
 
 require (
+	github.com/a-h/templ v0.2.747
 	github.com/google/uuid v1.6.0
 	github.com/labstack/echo/v4 v4.12.0
 	github.com/wk8/go-ordered-map/v2 v2.1.8
 )
 
 require (
-	github.com/a-h/templ v0.2.747 // indirect
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"jay/tictactoe/internal/events"
+	"jay/tictactoe/model"
+	tictactoe "jay/tictactoe/pkg"
+	"jay/tictactoe/pkg/engine"
+	"log"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Default time a bot waits before playing its move
+const BotThinkingDelay = 600 * time.Millisecond
+
+func botId(game *model.ServerGame) tictactoe.ParticipantId {
+	return tictactoe.ParticipantId(fmt.Sprintf("bot-%d", game.Id))
+}
+
+// Reads the computer opponent picked for a new game, nil when it's played by
+// people
+func (this *Server) botFromForm(c echo.Context, game *tictactoe.Game) (*model.BotSeat, error) {
+	opponent := c.FormValue("opponent")
+	if opponent == "" || opponent == "human" {
+		return nil, nil
+	}
+	if game.Variant != tictactoe.Classic {
+		return nil, errors.New("Computer opponents only play classic games")
+	}
+	if game.ThreePlayers {
+		return nil, errors.New("Computer opponents only play two player games")
+	}
+	level, err := engine.ParseLevel(opponent)
+	if err != nil {
+		return nil, err
+	}
+	if err := level.CheckBoard(&game.Board); err != nil {
+		return nil, err
+	}
+	return &model.BotSeat{Bot: engine.NewBot(level), Delay: this.BotDelay}, nil
+}
+
+// Seats the game's bot once a human is sitting in the first seat and starts
+// listening for its turn. Must be called with this.mu held
+func (this *Server) seatBot(game *model.ServerGame) {
+	seat := game.Bot
+	if seat == nil || seat.Participant != nil || game.Player1 == nil || game.Player2 != nil {
+		return
+	}
+
+	id := botId(game)
+	game.JoinBot(id, fmt.Sprintf("Computer (%s)", seat.Level))
+	seat.Participant, _ = game.Participants.Get(id)
+	this.startBot(game)
+}
+
+// Starts listening for the turns of a bot that has been seated. Must be
+// called with this.mu held
+func (this *Server) startBot(game *model.ServerGame) {
+	// Buffered so that the fan-out goroutine never waits on the bot
+	listener := make(chan *model.GamePlayEvent, 8)
+	game.Listeners[game.Bot.Participant.Id] = map[chan<- *model.GamePlayEvent]struct{}{listener: {}}
+	go this.runBot(game, listener)
+}
+
+// Plays the bot's moves until the game is over. Events only wake the bot up,
+// the position is always read from the game itself
+func (this *Server) runBot(game *model.ServerGame, listener chan *model.GamePlayEvent) {
+	wake := make(chan struct{}, 1)
+	go func() {
+		for range listener {
+			select {
+			case wake <- struct{}{}:
+			default:
+				// Already woken up, it will see the latest position
+			}
+		}
+		close(wake)
+	}()
+
+	for range wake {
+		if this.playBotMove(game) {
+			break
+		}
+	}
+
+	this.mu.Lock()
+	delete(game.Listeners, game.Bot.Participant.Id)
+	this.mu.Unlock()
+	close(listener)
+}
+
+// Plays the bot's move if it is its turn. Returns true once the game is over
+func (this *Server) playBotMove(game *model.ServerGame) bool {
+	seat := game.Bot
+
+	this.mu.Lock()
+	if game.GameOver() {
+		this.mu.Unlock()
+		return true
+	}
+	if game.State != tictactoe.InProgress || game.CurrentPlayer != seat.Participant {
+		this.mu.Unlock()
+		return false
+	}
+	board, player := game.Board, game.CurrentPlayerValue()
+	this.mu.Unlock()
+
+	time.Sleep(seat.Delay)
+	cell, err := seat.Move(board, player)
+	if err != nil {
+		log.Println("Bot could not find a move:", err)
+		return false
+	}
+
+	this.mu.Lock()
+	if game.Board != board || game.State != tictactoe.InProgress || game.CurrentPlayer != seat.Participant {
+		// The position changed while the bot was thinking, e.g. a takeback
+		// and a different move leave as many moves as before
+		this.mu.Unlock()
+		return false
+	}
+	err = game.PlayMove(player, cell)
+	state := game.State
+	if err != nil {
+		this.mu.Unlock()
+		log.Println("Bot played an invalid move:", err)
+		// The move can still have ended the game, e.g. on time
+		this.stateEvents(game, tictactoe.InProgress, state)
+		return state.Terminal()
+	}
+	move := *game.LastMove()
+	this.mu.Unlock()
+
+	this.moveEvents(game, move, tictactoe.InProgress, state)
+	return state.Terminal()
+}
+
+// Publishes a move and the game's new state if the move ended it
+func (this *Server) moveEvents(game *model.ServerGame, move tictactoe.Move, from tictactoe.GameState, to tictactoe.GameState) {
+	this.GamePlay <- &model.GamePlayEvent{
+		GameId:    game.Id,
+		Info:      fmt.Sprintf("Player %d played at cell %d", move.Player, move.Cell),
+		EventType: events.MovePlayed,
+		Move:      &move,
+	}
+	this.stateEvents(game, from, to)
+}
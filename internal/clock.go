@@ -0,0 +1,47 @@
+package server
+
+import (
+	"jay/tictactoe/internal/events"
+	"jay/tictactoe/model"
+	"time"
+)
+
+// How often viewers of a timed game get the time left on the clocks
+const ClockTickInterval = time.Second
+
+// Keeps a started timed game's clocks going until the game is over: sends a
+// tick to its viewers every ClockTickInterval while a clock runs and forfeits
+// the game as soon as the player to move runs out of time, even when nobody
+// plays a move
+func (this *Server) runClock(game *model.ServerGame) {
+	for {
+		this.mu.Lock()
+		if game.GameOver() {
+			this.mu.Unlock()
+			return
+		}
+		before := game.State
+		flagged := game.CheckTime()
+		after := game.State
+		running := !flagged && game.ClockRunning(game.CurrentPlayerValue())
+		wait := ClockTickInterval
+		if running {
+			// Wake up right when the flag falls
+			wait = min(wait, game.TimeLeft(game.CurrentPlayerValue()))
+		}
+		this.mu.Unlock()
+
+		if flagged {
+			this.stateEvents(game, before, after)
+			return
+		}
+		if running {
+			this.GamePlay <- &model.GamePlayEvent{
+				GameId:    game.Id,
+				Info:      "Clock tick",
+				EventType: events.ClockTick,
+			}
+		}
+		time.Sleep(wait)
+	}
+}
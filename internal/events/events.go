@@ -10,4 +10,15 @@ const (
 	SpectatorLeft
 	MovePlayed
 	GameOver
+	StateChanged
+	ClockTick
+	PlayerResigned
+	DrawOffered
+	DrawAccepted
+	DrawDeclined
+	RematchOffered
+	RematchStarted
+	TakebackRequested
+	TakebackAccepted
+	TakebackDeclined
 )
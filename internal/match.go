@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"jay/tictactoe/internal/events"
+	"jay/tictactoe/model"
+	tictactoe "jay/tictactoe/pkg"
+	"jay/tictactoe/view"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Time the players get to look at a finished game of a match before the next
+// one starts
+const NextMatchGameDelay = 3 * time.Second
+
+// Registers the game that follows a finished one, a rematch or the next game
+// of a match, and holds it until the players followed the redirect. Must be
+// called with this.mu held
+func (this *Server) addNextGame(game *model.ServerGame, next *tictactoe.Game) *model.ServerGame {
+	nextGame := this.serverGameFor(next)
+	if bot := game.Bot; bot != nil {
+		nextGame.Bot = &model.BotSeat{Bot: bot.Bot, Delay: bot.Delay, Participant: next.SeatedPlayer(bot.Participant.Id)}
+		this.startBot(nextGame)
+	}
+	next.Pause()
+	this.awaitReconnect(nextGame)
+	this.Games[nextGame.Id] = nextGame
+	return nextGame
+}
+
+// Sends the viewers of a finished game over to the game that follows it
+func (this *Server) nextGameEvents(game *model.ServerGame, next *model.ServerGame) {
+	if next.Clocks != nil {
+		go this.runClock(next)
+	}
+	this.GamePlay <- &model.GamePlayEvent{
+		GameId:    game.Id,
+		Info:      fmt.Sprintf("Game %d follows game %d", next.Id, game.Id),
+		EventType: events.RematchStarted,
+	}
+	this.GameStatus <- &model.GameStatusEvent{GameId: next.Id, Info: "Next game created", State: next.State}
+}
+
+// Starts the next game of a match after a short break, does nothing once
+// the match is over
+func (this *Server) nextMatchGame(game *model.ServerGame) {
+	time.Sleep(NextMatchGameDelay)
+
+	this.mu.Lock()
+	match := game.Match
+	if match == nil || match.Current() != game.Game || match.Over() {
+		this.mu.Unlock()
+		return
+	}
+	next, err := match.NextGame()
+	var nextGame *model.ServerGame
+	if err == nil {
+		nextGame = this.addNextGame(game, next)
+	}
+	this.mu.Unlock()
+	if err != nil {
+		log.Println("Could not start the next game of match", match.Id, err)
+		return
+	}
+	this.nextGameEvents(game, nextGame)
+}
+
+func (this *Server) getMatch(c echo.Context) (*tictactoe.Match, error) {
+	matchId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+	if match, exists := this.Matches[tictactoe.MatchId(matchId)]; exists {
+		return match, nil
+	}
+	return nil, errors.New("Match not found")
+}
+
+// Shows the score of a match above the live board of its current game
+func (this *Server) MatchDisplayHandler(c echo.Context) error {
+	match, err := this.getMatch(c)
+	if err != nil {
+		return err
+	}
+	clientId, err := this.GetClientId(c)
+	if err != nil {
+		clientId, err = setClientCookie(c)
+		if err != nil {
+			return errors.New("Could not set client cookie")
+		}
+	}
+
+	return render(c, view.Match(match, clientId))
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"jay/tictactoe/model"
+	tictactoe "jay/tictactoe/pkg"
+	"strings"
+	"time"
+)
+
+// How long a paused game waits for its players to come back before it is
+// abandoned
+const ReconnectDeadline = 2 * time.Minute
+
+// Abandons a paused game unless every player is back before the reconnect
+// deadline, a later pause starts the wait over. Must be called with this.mu
+// held
+func (this *Server) awaitReconnect(game *model.ServerGame) {
+	if game.Reconnect != nil {
+		game.Reconnect.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(this.ReconnectDeadline, func() {
+		this.mu.Lock()
+		before := game.State
+		if game.Reconnect != timer || before != tictactoe.Paused || this.playersConnected(game) {
+			this.mu.Unlock()
+			return
+		}
+		game.Reconnect = nil
+		game.Abandon(missingPlayers(game) + " did not come back")
+		after := game.State
+		this.mu.Unlock()
+		this.stateEvents(game, before, after)
+	})
+	game.Reconnect = timer
+}
+
+// Names the seated players without a connection, e.g. "alice and bob"
+func missingPlayers(game *model.ServerGame) string {
+	var names []string
+	for _, p := range game.Seats() {
+		if p != nil && !p.Connected {
+			names = append(names, p.Name)
+		}
+	}
+	return strings.Join(names, " and ")
+}
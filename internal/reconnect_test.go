@@ -0,0 +1,65 @@
+package server
+
+import (
+	"jay/tictactoe/model"
+	tictactoe "jay/tictactoe/pkg"
+	"testing"
+	"time"
+)
+
+// pausedGame registers a game between alice and bob that was paused when bob
+// left
+func pausedGame(t *testing.T, s *Server) *model.ServerGame {
+	game := tictactoe.NewGame(0)
+	game.Join("alice", "alice")
+	game.Join("bob", "bob")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	serverGame := s.serverGameFor(game)
+	s.Games[serverGame.Id] = serverGame
+	game.Player2.Connected = false
+	if err := game.PauseFor(game.Player2); err != nil {
+		t.Fatal(err)
+	}
+	s.awaitReconnect(serverGame)
+	return serverGame
+}
+
+func testServer() *Server {
+	s := NewServer()
+	s.ReconnectDeadline = 20 * time.Millisecond
+	go s.ListenForGameplayEvents()
+	go s.ListenForGameStatusEvents()
+	return s
+}
+
+func TestReconnectDeadlineAbandonsGame(t *testing.T) {
+	s := testServer()
+	game := pausedGame(t, s)
+	time.Sleep(5 * s.ReconnectDeadline)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if game.State != tictactoe.Abandoned {
+		t.Fatalf("game is %s after the reconnect deadline", game.State)
+	}
+	if reason := game.Outcome.Reason; reason != "bob did not come back" {
+		t.Errorf("game abandoned because %q", reason)
+	}
+}
+
+func TestReconnectBeforeDeadline(t *testing.T) {
+	s := testServer()
+	game := pausedGame(t, s)
+	s.mu.Lock()
+	game.Join("bob", "bob")
+	game.Resume()
+	s.mu.Unlock()
+	time.Sleep(5 * s.ReconnectDeadline)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if game.State != tictactoe.InProgress {
+		t.Errorf("game is %s after bob came back in time", game.State)
+	}
+}
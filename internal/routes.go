@@ -7,6 +7,7 @@ import (
 	"jay/tictactoe/internal/events"
 	"jay/tictactoe/model"
 	tictactoe "jay/tictactoe/pkg"
+	"jay/tictactoe/pkg/engine"
 	"jay/tictactoe/view"
 	"jay/tictactoe/view/shared"
 	"log"
@@ -14,6 +15,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/a-h/templ"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
@@ -28,36 +30,47 @@ func (this *Server) GameHistoryHandler(c echo.Context) error {
 	if err != nil {
 		return err
 	}
-	gameHistoryControls := model.GameHistoryControls{
-		Id:            game.Id,
-		BackOffset:    offset - 1,
-		Offset:        offset,
-		ForwardOffset: offset + 1,
-		CanGoBack:     offset*-1 < len(game.History),
-		CanGoForward:  offset < 0,
-		AtCurrent:     offset == 0,
-		Oob:           true,
-	}
-	type ControlsData struct {
-		tictactoe.Board
-		model.GameHistoryControls
-	}
-	board := game.Board
-	if offset < 0 {
-		board = game.History[len(game.History)+offset]
-	}
-	data := &ControlsData{board, gameHistoryControls}
-	err = c.Echo().Renderer.Render(
-		c.Response().Writer,
-		"board-history",
-		data,
-		c,
-	)
+	gameHistoryControls := model.NewGameHistoryControls(game.Game, offset)
+	gameHistoryControls.Oob = true
+	var line *tictactoe.Line
+	if gameHistoryControls.AtCurrent {
+		line = game.WinningLine()
+	}
+	board, err := positionOob(game, gameHistoryControls.Ply, line)
+	if err != nil {
+		return err
+	}
+	err = render(c, board)
 	if err != nil {
 		return err
 	}
 
-	return c.Render(200, "history-controls", &gameHistoryControls)
+	return render(c, shared.History(gameHistoryControls))
+}
+
+// Renders the game's board as it was after the given number of moves, drawn
+// the way the game's variant is
+func positionOob(game *model.ServerGame, ply int, line *tictactoe.Line) (templ.Component, error) {
+	if game.Variant == tictactoe.Ultimate {
+		ultimate, err := game.UltimateBoardAt(ply)
+		if err != nil {
+			return nil, err
+		}
+		return shared.UltimateBoardOob(ultimate, game.Id, line), nil
+	}
+
+	board, err := game.BoardAt(ply)
+	if err != nil {
+		return nil, err
+	}
+	if game.Variant == tictactoe.Qubic {
+		qubic, err := tictactoe.QubicBoardFrom(&board)
+		if err != nil {
+			return nil, err
+		}
+		return shared.QubicBoardOob(qubic, game.Id, line), nil
+	}
+	return shared.BoardOob(&board, game.Id, line), nil
 }
 
 func (this *Server) GameDisplayHandler(c echo.Context) error {
@@ -145,17 +158,17 @@ func (this *Server) GameHandler(c echo.Context) error {
 
 	gameListener := make(chan *model.GamePlayEvent)
 	this.mu.Lock()
+	before := game.State
 	playerJoined := game.Join(clientId, string(clientId))
 	eventType := events.SpectatorJoined
 	if playerJoined {
-		this.GameStatus <- &model.GameStatusEvent{GameId: game.Id, Info: "Player joined"}
+		this.seatBot(game)
 		eventType = events.PlayerJoined
+	} else if game.State == tictactoe.Paused && this.playersConnected(game) {
+		// A player that dropped out came back
+		game.Resume()
 	}
-	this.GamePlay <- &model.GamePlayEvent{
-		GameId:    game.Id,
-		Info:      fmt.Sprintf("Client %s joined game (%s)", clientId, sessionIdStr),
-		EventType: eventType,
-	}
+	after := game.State
 	// clientListeners, exists := this.ActiveGameListeners[clientId]
 	clientListeners, exists := this.Games[game.Id].Listeners[clientId]
 	if !exists {
@@ -164,6 +177,12 @@ func (this *Server) GameHandler(c echo.Context) error {
 	}
 	clientListeners[gameListener] = struct{}{}
 	this.mu.Unlock()
+	this.GamePlay <- &model.GamePlayEvent{
+		GameId:    game.Id,
+		Info:      fmt.Sprintf("Client %s joined game (%s)", clientId, sessionIdStr),
+		EventType: eventType,
+	}
+	this.stateEvents(game, before, after)
 
 	// Send full page content in case client gets disconnected without refreshing page
 	template, err := renderToString(c, view.GamePartial(game.Game, clientId))
@@ -176,10 +195,19 @@ func (this *Server) GameHandler(c echo.Context) error {
 		this.mu.Lock()
 		// Close listener and then mark player as disconnected if the number of listeners is 0
 		delete(clientListeners, gameListener)
+		before := game.State
 		p, exists := game.Participants.Get(clientId)
 		if exists && len(clientListeners) == 0 {
 			p.Connected = false
+			if p.Player && game.State == tictactoe.InProgress {
+				// Hold the game until the player reconnects
+				game.PauseFor(p)
+			}
+			if p.Player && game.State == tictactoe.Paused {
+				this.awaitReconnect(game)
+			}
 		}
+		after := game.State
 		this.mu.Unlock()
 		close(gameListener)
 		eventType := events.SpectatorLeft
@@ -191,6 +219,7 @@ func (this *Server) GameHandler(c echo.Context) error {
 			Info:      fmt.Sprintf("Client %s disconnected (%s)", clientId, sessionIdStr),
 			EventType: eventType,
 		}
+		this.stateEvents(game, before, after)
 	}
 
 listenerLoop:
@@ -211,16 +240,238 @@ listenerLoop:
 }
 
 func (this *Server) NewGameHandler(c echo.Context) error {
+	newGame, err := gameFromForm(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	bot, err := this.botFromForm(c, newGame)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if value := c.FormValue("time"); value != "" && value != "none" {
+		control, err := tictactoe.ParseTimeControl(value)
+		if err == nil {
+			err = newGame.SetTimeControl(control, this.Clock)
+		}
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	var match *tictactoe.Match
+	if value := c.FormValue("best_of"); value != "" && value != "1" {
+		bestOf, err := strconv.Atoi(value)
+		if err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Invalid number of games %q", value))
+		}
+		if match, err = tictactoe.NewMatch(0, bestOf, newGame); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if c.FormValue("undo") != "" {
+		if !newGame.Casual() {
+			return c.String(http.StatusBadRequest, "Unlimited undo is only for casual games")
+		}
+		newGame.UnlimitedUndo = true
+	}
+
 	this.mu.Lock()
-	game := this.newServerGame()
+	game := this.serverGameFor(newGame)
+	game.EarlyDraws = c.FormValue("early_draws") != ""
+	game.Bot = bot
 	this.Games[game.Id] = game
+	if match != nil {
+		match.Id = tictactoe.MatchId(this.matchCount.Add(1))
+		this.Matches[match.Id] = match
+	}
 	this.mu.Unlock()
 	// log.Println("New game created. Total games:", len(tictactoe.Games))
 	this.GameStatus <- &model.GameStatusEvent{GameId: game.Id, Info: "New game created"}
-	return c.Render(http.StatusOK, "game-list", this)
+	return render(c, view.GameList(this.gameList()))
 	// return c.Render(http.StatusOK, "game-card", game)
 }
 
+func (this *Server) ResignHandler(c echo.Context) error {
+	return this.playerAction(c, events.PlayerResigned, "resigned", (*tictactoe.Game).Resign)
+}
+
+func (this *Server) OfferDrawHandler(c echo.Context) error {
+	return this.playerAction(c, events.DrawOffered, "offered a draw", (*tictactoe.Game).OfferDraw)
+}
+
+func (this *Server) AcceptDrawHandler(c echo.Context) error {
+	return this.playerAction(c, events.DrawAccepted, "accepted the draw", (*tictactoe.Game).AcceptDraw)
+}
+
+func (this *Server) DeclineDrawHandler(c echo.Context) error {
+	return this.playerAction(c, events.DrawDeclined, "declined the draw", (*tictactoe.Game).DeclineDraw)
+}
+
+// Asks for a takeback, games with unlimited undo and bots take the move back
+// right away
+func (this *Server) TakebackHandler(c echo.Context) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+	clientId, _ := this.GetClientId(c)
+
+	this.mu.Lock()
+	player := game.SeatedPlayer(clientId)
+	if player == nil {
+		this.mu.Unlock()
+		return c.String(http.StatusForbidden, "You are not a player in this game")
+	}
+	undone, err := game.RequestTakeback(player)
+	if bot := game.Bot; err == nil && !undone && bot != nil && bot.Participant != nil {
+		err = game.AcceptTakeback(bot.Participant)
+		undone = err == nil
+	}
+	this.mu.Unlock()
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	event := &model.GamePlayEvent{
+		GameId:    game.Id,
+		Info:      fmt.Sprintf("Player %s asked for a takeback", player.Name),
+		EventType: events.TakebackRequested,
+	}
+	if undone {
+		event.Info = fmt.Sprintf("Player %s took back their move", player.Name)
+		event.EventType = events.TakebackAccepted
+	}
+	this.GamePlay <- event
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *Server) AcceptTakebackHandler(c echo.Context) error {
+	return this.playerAction(c, events.TakebackAccepted, "approved the takeback", (*tictactoe.Game).AcceptTakeback)
+}
+
+func (this *Server) DeclineTakebackHandler(c echo.Context) error {
+	return this.playerAction(c, events.TakebackDeclined, "declined the takeback", (*tictactoe.Game).DeclineTakeback)
+}
+
+// Runs an action for the player that sent the request and tells the game's
+// viewers about it, spectators cannot act
+func (this *Server) playerAction(c echo.Context, eventType events.GamePlayEventType, done string, action func(*tictactoe.Game, *tictactoe.Participant) error) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+	clientId, _ := this.GetClientId(c)
+
+	this.mu.Lock()
+	player := game.SeatedPlayer(clientId)
+	if player == nil {
+		this.mu.Unlock()
+		return c.String(http.StatusForbidden, "You are not a player in this game")
+	}
+	before := game.State
+	err = action(game.Game, player)
+	after := game.State
+	this.mu.Unlock()
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	this.GamePlay <- &model.GamePlayEvent{
+		GameId:    game.Id,
+		Info:      fmt.Sprintf("Player %s %s", player.Name, done),
+		EventType: eventType,
+	}
+	this.stateEvents(game, before, after)
+	return c.NoContent(http.StatusOK)
+}
+
+// Asks for a rematch, starts it once every player asked for one. Bots always
+// agree
+func (this *Server) RematchHandler(c echo.Context) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+	clientId, _ := this.GetClientId(c)
+
+	this.mu.Lock()
+	player := game.SeatedPlayer(clientId)
+	if player == nil {
+		this.mu.Unlock()
+		return c.String(http.StatusForbidden, "You are not a player in this game")
+	}
+	next, err := game.OfferRematch(player)
+	if bot := game.Bot; err == nil && next == nil && bot != nil && bot.Participant != nil {
+		next, err = game.OfferRematch(bot.Participant)
+	}
+	var rematch *model.ServerGame
+	if err == nil && next != nil {
+		rematch = this.addNextGame(game, next)
+	}
+	this.mu.Unlock()
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if rematch == nil {
+		this.GamePlay <- &model.GamePlayEvent{
+			GameId:    game.Id,
+			Info:      fmt.Sprintf("Player %s asked for a rematch", player.Name),
+			EventType: events.RematchOffered,
+		}
+		return c.NoContent(http.StatusOK)
+	}
+	this.nextGameEvents(game, rematch)
+	return c.NoContent(http.StatusOK)
+}
+
+// Starts a new game from the position after the given number of moves and
+// sends the client there
+func (this *Server) ForkHandler(c echo.Context) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+	ply, err := strconv.Atoi(c.Param("ply"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, fmt.Sprintf("Invalid move number %q", c.Param("ply")))
+	}
+
+	this.mu.Lock()
+	fork, err := game.Fork(ply)
+	if err != nil {
+		this.mu.Unlock()
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	forked := this.serverGameFor(fork)
+	this.Games[forked.Id] = forked
+	this.mu.Unlock()
+	this.GameStatus <- &model.GameStatusEvent{
+		GameId: forked.Id,
+		Info:   fmt.Sprintf("Game continued from game %d after move %d", game.Id, ply),
+	}
+	return redirect(c, fmt.Sprintf("/games/%d", forked.Id))
+}
+
+// Sends the client to the rematch of a game, viewers request it once they
+// hear that the rematch started
+func (this *Server) RematchRedirectHandler(c echo.Context) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+	if game.Rematch == nil {
+		return c.String(http.StatusNotFound, "There is no rematch of this game")
+	}
+	if match := game.Rematch.Match; match != nil {
+		return redirect(c, fmt.Sprintf("/matches/%d", match.Id))
+	}
+	return redirect(c, fmt.Sprintf("/games/%d", game.Rematch.Id))
+}
+
 func (this *Server) GameBoardHandler(c echo.Context) error {
 	game, err := this.getGame(c)
 	if err != nil {
@@ -246,39 +497,106 @@ func (this *Server) PlayerMoveHandler(c echo.Context) error {
 	cellIdxStr := c.FormValue("i")
 	cellIdx, _ := strconv.Atoi(cellIdxStr)
 	clientId, _ := this.GetClientId(c)
-	isPlayer1 := game.Player1.Id == clientId
-	isPlayer2 := game.Player2.Id == clientId
-	playerValue := 0b01
-	if !isPlayer1 && !isPlayer2 {
+	playerValue := game.PlayerValue(game.SeatedPlayer(clientId))
+	if playerValue == 0 {
 		return c.String(http.StatusForbidden, "You are not a player in this game")
 	}
-	if !isPlayer1 {
-		playerValue = 0b10
-	}
 
 	// err = game.PlayMove(playerValue, cellIdx, gamePlay)
-	err = game.PlayMove(playerValue, cellIdx)
+	this.mu.Lock()
+	before := game.State
+	piece := game.Rules().Piece(playerValue)
+	if symbol := c.FormValue("piece"); symbol != "" {
+		piece, err = tictactoe.ParsePiece(symbol)
+	}
+	if err == nil {
+		err = game.PlayPiece(playerValue, cellIdx, piece)
+	}
+	var move tictactoe.Move
+	if err == nil {
+		move = *game.LastMove()
+	}
+	after := game.State
+	this.mu.Unlock()
 	// fmt.Println(game.Board.String())
 	if err != nil {
+		// The move can still end the game when the player ran out of time
+		this.stateEvents(game, before, after)
 		return c.String(http.StatusBadRequest, err.Error())
 	} else {
-		this.GamePlay <- &model.GamePlayEvent{
-			GameId:    game.Id,
-			Info:      fmt.Sprintf("Player %d played at cell %d", playerValue, cellIdx),
-			EventType: events.MovePlayed,
-		}
+		this.moveEvents(game, move, before, after)
 	}
 	// cell := game.GetCell(cellIdx)
 	// return c.Render(http.StatusOK, "cell", cell)
 	return c.NoContent(http.StatusOK)
 }
 
+func (this *Server) GameAnalysisHandler(c echo.Context) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+	if c.QueryParam("hide") != "" {
+		return render(c, shared.AnalysisPlaceholder(game.Id))
+	}
+	if game.Variant != tictactoe.Classic || game.ThreePlayers {
+		return c.String(http.StatusBadRequest, "Analysis is only available for classic two player games")
+	}
+
+	this.mu.Lock()
+	board, player, over := game.Board, game.CurrentPlayerValue(), game.GameOver()
+	if player == 0 {
+		player = game.Rules().SideToMove(game.Game)
+	}
+	this.mu.Unlock()
+
+	var cells []engine.CellAnalysis
+	if !over {
+		cells = engine.Analyze(board, player)
+	}
+	analysis := model.NewGameAnalysis(game.Game, player, cells)
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, analysis)
+	}
+	return render(c, shared.Analysis(analysis))
+}
+
+func (this *Server) GameExportHandler(c echo.Context) error {
+	game, err := this.getGame(c)
+	if err != nil {
+		return err
+	}
+
+	this.mu.Lock()
+	notation := game.Notation()
+	this.mu.Unlock()
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"game-%d.txt\"", game.Id))
+	return c.String(http.StatusOK, notation)
+}
+
+func (this *Server) GameImportHandler(c echo.Context) error {
+	imported, err := tictactoe.ParseNotation(c.FormValue("notation"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if !imported.GameOver() {
+		return c.String(http.StatusBadRequest, "Only finished games can be imported")
+	}
+
+	this.mu.Lock()
+	game := this.serverGameFor(imported)
+	this.Games[game.Id] = game
+	this.mu.Unlock()
+	this.GameStatus <- &model.GameStatusEvent{GameId: game.Id, Info: "Game imported"}
+	return redirect(c, fmt.Sprintf("/games/%d", game.Id))
+}
+
 func (this *Server) IndexHandler(c echo.Context) error {
 	return render(c, view.Index(this.gameList()))
 }
 
 func (this *Server) GameListHandler(c echo.Context) error {
-	return c.Render(http.StatusOK, "game-list", this)
+	return render(c, view.GameList(this.gameList()))
 }
 
 // func renderTemplate(name string, data interface{}, c echo.Context) (string, error) {
@@ -311,18 +629,68 @@ func processGameEvent(c echo.Context, event *model.GamePlayEvent, game *model.Se
 			sendSse("clients", t, c)
 		}
 	case events.MovePlayed:
-		_, idx := game.LastMove()
+		idx := event.Move.Cell
 		// t, err := renderTemplate("cell", game.GetCell(idx), c)
-		t, err := renderToString(c, shared.Cell(game.GetCell(idx), game.Id, false))
+		t, err := renderToString(c, shared.Cell(game.GetCell(idx), game.Id, false, false))
 		if err != nil {
 			sendError(err)
 		}
 
 		time.Sleep(200 * time.Millisecond)
 		sendSse(fmt.Sprintf("cell_%d", idx), t, c)
-
-		if game.GameOver() {
-			sendSse("game_over", "", c)
+		if game.Variant == tictactoe.Ultimate {
+			// The move decides which small board is played next
+			t, err := renderToString(c, shared.UltimateBoard(game.Game))
+			if err != nil {
+				sendError(err)
+			} else {
+				sendSse("ultimate_board", t, c)
+			}
+		}
+		sendSse("move_played", "", c)
+		sendClocks(c, game, sendError)
+		// Whose turn it is and pending draw offers changed
+		sendClients(c, game, clientId, "clients", sendError)
+	case events.ClockTick:
+		sendClocks(c, game, sendError)
+	case events.TakebackAccepted:
+		// The board and whose turn it is went back
+		t, err := renderToString(c, shared.Takeback(game.Game))
+		if err != nil {
+			sendError(err)
+		} else {
+			sendSse("takeback", t, c)
+		}
+		sendClients(c, game, clientId, "takeback_accepted", sendError)
+	case events.RematchStarted:
+		// Viewers ask where the rematch is and get redirected there
+		sendSse("rematch", "", c)
+	case events.PlayerResigned, events.DrawOffered, events.DrawAccepted, events.DrawDeclined, events.RematchOffered,
+		events.TakebackRequested, events.TakebackDeclined:
+		// The sidebar shows the pending draw offer and the actions left
+		sendClients(c, game, clientId, actionEvents[event.EventType], sendError)
+	case events.GameOver:
+		sendGameOver(c, game, sendError)
+	case events.StateChanged:
+		sendClocks(c, game, sendError)
+		// The players' actions depend on the state
+		sendClients(c, game, clientId, "clients", sendError)
+		if game.Match != nil && event.State.Terminal() {
+			if t, err := renderToString(c, shared.MatchScore(game.Game)); err != nil {
+				sendError(err)
+			} else {
+				sendSse("match_score", t, c)
+			}
+		}
+		if event.State.Terminal() {
+			sendGameOver(c, game, sendError)
+			break
+		}
+		t, err := renderToString(c, shared.Status(game.Game))
+		if err != nil {
+			sendError(err)
+		} else {
+			sendSse("status", t, c)
 		}
 
 	default:
@@ -332,6 +700,51 @@ func processGameEvent(c echo.Context, event *model.GamePlayEvent, game *model.Se
 	return true
 }
 
+// Sends the sidebar as the client sees it under the given event name
+func sendClients(c echo.Context, game *model.ServerGame, clientId tictactoe.ParticipantId, eventName string, sendError func(error)) {
+	t, err := renderToString(c, shared.Clients(game.Game, clientId))
+	if err != nil {
+		sendError(err)
+		return
+	}
+	sendSse(eventName, t, c)
+}
+
+// SSE event names of the players' actions
+var actionEvents = map[events.GamePlayEventType]string{
+	events.PlayerResigned:    "resigned",
+	events.DrawOffered:       "draw_offered",
+	events.DrawAccepted:      "draw_accepted",
+	events.DrawDeclined:      "draw_declined",
+	events.RematchOffered:    "rematch_offered",
+	events.TakebackRequested: "takeback_requested",
+	events.TakebackDeclined:  "takeback_declined",
+}
+
+// Sends the final status of the game, clients also reload the board and
+// history controls when they receive it
+func sendGameOver(c echo.Context, game *model.ServerGame, sendError func(error)) {
+	t, err := renderToString(c, shared.GameOver(game.Game))
+	if err != nil {
+		sendError(err)
+		return
+	}
+	sendSse("game_over", t, c)
+}
+
+// Sends the time left of every player of a timed game
+func sendClocks(c echo.Context, game *model.ServerGame, sendError func(error)) {
+	if game.Clocks == nil {
+		return
+	}
+	t, err := renderToString(c, shared.Clocks(game.Game))
+	if err != nil {
+		sendError(err)
+		return
+	}
+	sendSse("clocks", t, c)
+}
+
 func (this *Server) gameList() []*tictactoe.Game {
 
 	var games []*tictactoe.Game
@@ -2,6 +2,8 @@ package server
 
 import (
 	"errors"
+	"fmt"
+	"jay/tictactoe/internal/events"
 	"jay/tictactoe/model"
 	tictactoe "jay/tictactoe/pkg"
 	"log"
@@ -10,6 +12,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -21,17 +24,26 @@ const DEBUG = true
 
 type Server struct {
 	Games          map[tictactoe.GameId]*model.ServerGame
+	Matches        map[tictactoe.MatchId]*tictactoe.Match
 	IndexListeners map[chan<- *model.GameStatusEvent]struct{}
 	GamePlay       chan *model.GamePlayEvent
 	GameStatus     chan *model.GameStatusEvent
-	mu             sync.Mutex
-	gameCount      atomic.Uint32
+	BotDelay       time.Duration
+	// Time players get to come back to a game paused without them
+	ReconnectDeadline time.Duration
+	Clock             tictactoe.Clock // Time source of timed games
+	mu                sync.Mutex
+	gameCount         atomic.Uint32
+	matchCount        atomic.Uint32
 }
 
-func (this *Server) newServerGame() *model.ServerGame {
-	game := tictactoe.NewGame(
-		tictactoe.GameId(this.gameCount.Add(1)),
-	)
+func (this *Server) newServerGame(board *tictactoe.Board) *model.ServerGame {
+	return this.serverGameFor(tictactoe.NewGameWithBoard(0, board))
+}
+
+// Gives a game created elsewhere (e.g. imported) a fresh id on this server
+func (this *Server) serverGameFor(game *tictactoe.Game) *model.ServerGame {
+	game.Id = tictactoe.GameId(this.gameCount.Add(1))
 	return &model.ServerGame{
 		Game:      game,
 		Listeners: make(map[tictactoe.ParticipantId]map[chan<- *model.GamePlayEvent]struct{}),
@@ -41,10 +53,14 @@ func (this *Server) newServerGame() *model.ServerGame {
 func NewServer() *Server {
 
 	s := &Server{
-		Games:          make(map[tictactoe.GameId]*model.ServerGame),
-		IndexListeners: make(map[chan<- *model.GameStatusEvent]struct{}),
-		GamePlay:       make(chan *model.GamePlayEvent, 5),
-		GameStatus:     make(chan *model.GameStatusEvent, 5),
+		Games:             make(map[tictactoe.GameId]*model.ServerGame),
+		Matches:           make(map[tictactoe.MatchId]*tictactoe.Match),
+		IndexListeners:    make(map[chan<- *model.GameStatusEvent]struct{}),
+		GamePlay:          make(chan *model.GamePlayEvent, 5),
+		GameStatus:        make(chan *model.GameStatusEvent, 5),
+		BotDelay:          BotThinkingDelay,
+		ReconnectDeadline: ReconnectDeadline,
+		Clock:             tictactoe.SystemClock,
 	}
 
 	player1 := &tictactoe.Participant{Id: "t1", Name: "Testing 1", Player: true}
@@ -55,11 +71,12 @@ func NewServer() *Server {
 		Board:         *tictactoe.NewBoardWithValue(0b010101),
 		Player1:       player1,
 		Player2:       player2,
-		Winner:        player1,
 		CurrentPlayer: player1,
-		History: []tictactoe.Board{
-			*tictactoe.NewBoardWithValue(0b01),
-			*tictactoe.NewBoardWithValue(0b0101),
+		State:         tictactoe.Finished,
+		Moves: []tictactoe.Move{
+			{Player: 0b01, Cell: 0, Piece: 0b01, Ply: 1, Time: time.Now()},
+			{Player: 0b01, Cell: 1, Piece: 0b01, Ply: 2, Time: time.Now()},
+			{Player: 0b01, Cell: 2, Piece: 0b01, Ply: 3, Time: time.Now()},
 		},
 		Participants: orderedmap.New[tictactoe.ParticipantId, *tictactoe.Participant](
 			orderedmap.WithInitialData(orderedmap.Pair[tictactoe.ParticipantId, *tictactoe.Participant]{
@@ -68,13 +85,21 @@ func NewServer() *Server {
 			})),
 	}
 
+	g.Outcome = &tictactoe.Outcome{
+		Kind:   tictactoe.Win,
+		Winner: player1,
+		Reason: "Three in a row",
+		Move:   &g.Moves[len(g.Moves)-1],
+		Line:   g.CheckWinner(),
+	}
+
 	sg := &model.ServerGame{
 		Game:      &g,
 		Listeners: make(map[tictactoe.ParticipantId]map[chan<- *model.GamePlayEvent]struct{}),
 	}
 
 	s.Games[sg.Id] = sg
-	sg = s.newServerGame()
+	sg = s.newServerGame(tictactoe.NewBoard())
 	s.Games[sg.Id] = sg
 
 	return s
@@ -100,7 +125,10 @@ func (this *Server) ClientIdMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 
 func (this *Server) ListenForGameplayEvents() {
 	for event := range this.GamePlay {
-		log.Println("Game play event received:", event)
+		if event.EventType != events.ClockTick {
+			// Ticks would drown out everything else
+			log.Println("Game play event received:", event)
+		}
 		this.mu.Lock()
 		game := this.Games[event.GameId]
 		for _, listeners := range game.Listeners {
@@ -123,6 +151,39 @@ func (this *Server) ListenForGameStatusEvents() {
 	}
 }
 
+// Publishes a game's new state to its viewers and the game list, does nothing
+// if the state did not change
+func (this *Server) stateEvents(game *model.ServerGame, from tictactoe.GameState, to tictactoe.GameState) {
+	if from == to {
+		return
+	}
+	info := fmt.Sprintf("Game went from %s to %s", from, to)
+	this.GamePlay <- &model.GamePlayEvent{
+		GameId:    game.Id,
+		Info:      info,
+		EventType: events.StateChanged,
+		State:     to,
+	}
+	this.GameStatus <- &model.GameStatusEvent{GameId: game.Id, Info: info, State: to}
+	if game.Clocks != nil && (from == tictactoe.Lobby || from == tictactoe.WaitingForOpponent) && to == tictactoe.InProgress {
+		// Clocks only need watching once the game started
+		go this.runClock(game)
+	}
+	if to.Terminal() && game.Match != nil {
+		go this.nextMatchGame(game)
+	}
+}
+
+// Reports whether every seated player has a live connection, bots always do
+func (this *Server) playersConnected(game *model.ServerGame) bool {
+	for _, p := range game.Seats() {
+		if p == nil || !p.Connected {
+			return false
+		}
+	}
+	return true
+}
+
 func setClientCookie(c echo.Context) (tictactoe.ParticipantId, error) {
 	id, err := uuid.NewV7()
 	if err != nil {
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"jay/tictactoe/model"
+	tictactoe "jay/tictactoe/pkg"
+	"jay/tictactoe/pkg/engine"
+	"jay/tictactoe/view"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Shows the setup editor for a board of the size in the query, 3x3 by default
+func (this *Server) SetupHandler(c echo.Context) error {
+	board, err := boardFromForm(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return render(c, view.Setup(board))
+}
+
+// Tells the editor whether a game can be played from the position. Problems
+// are part of the answer, so they are rendered like any other result
+func (this *Server) SetupCheckHandler(c echo.Context) error {
+	game, err := setupGameFromForm(c)
+	if err != nil {
+		return render(c, view.SetupProblem(err))
+	}
+	return render(c, view.SetupValid(game.Start))
+}
+
+// Starts a live game from the position and sends the client there
+func (this *Server) SetupGameHandler(c echo.Context) error {
+	newGame, err := setupGameFromForm(c)
+	if err != nil {
+		return render(c, view.SetupProblem(err))
+	}
+	bot, err := this.botFromForm(c, newGame)
+	if err != nil {
+		return render(c, view.SetupProblem(err))
+	}
+
+	this.mu.Lock()
+	game := this.serverGameFor(newGame)
+	game.Bot = bot
+	this.Games[game.Id] = game
+	this.mu.Unlock()
+	this.GameStatus <- &model.GameStatusEvent{GameId: game.Id, Info: "New game created from a set up position"}
+	return redirect(c, fmt.Sprintf("/games/%d", game.Id))
+}
+
+// Shows the engine's verdict on every empty cell of the position, next to
+// the editor
+func (this *Server) SetupAnalysisHandler(c echo.Context) error {
+	game, err := setupGameFromForm(c)
+	if err != nil {
+		return render(c, view.SetupProblem(err))
+	}
+	if game.Variant != tictactoe.Classic {
+		return render(c, view.SetupProblem(errors.New("Analysis is only available for classic games")))
+	}
+	player := game.Start.ToMove
+	cells := engine.Analyze(game.Board, player)
+	return render(c, view.SetupAnalysis(game, model.NewGameAnalysis(game, player, cells)))
+}
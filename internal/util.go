@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	tictactoe "jay/tictactoe/pkg"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
@@ -36,6 +40,111 @@ func renderToString(c echo.Context, component templ.Component) (string, error) {
 
 func UNUSED(x ...interface{}) {}
 
+// Redirects htmx requests with HX-Redirect and everything else with a 303
+func redirect(c echo.Context, url string) error {
+	if c.Request().Header.Get("HX-Request") != "" {
+		c.Response().Header().Set("HX-Redirect", url)
+		return c.NoContent(http.StatusOK)
+	}
+	return c.Redirect(http.StatusSeeOther, url)
+}
+
+// Whether the client asked for JSON instead of html
+func wantsJSON(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON)
+}
+
+// Creates a game of the variant picked in the new game form, variants that
+// can be played on any board read its size and number of players from the
+// form as well
+func gameFromForm(c echo.Context) (*tictactoe.Game, error) {
+	variant := tictactoe.Classic
+	if value := c.FormValue("variant"); value != "" {
+		var err error
+		if variant, err = tictactoe.ParseVariant(value); err != nil {
+			return nil, err
+		}
+	}
+	var game *tictactoe.Game
+	switch variant {
+	case tictactoe.Ultimate:
+		game = tictactoe.NewUltimateGame(0)
+	case tictactoe.Qubic:
+		game = tictactoe.NewQubicGame(0)
+	default:
+		board, err := boardFromForm(c)
+		if err != nil {
+			return nil, err
+		}
+		game = tictactoe.NewGameWithBoard(0, board)
+		game.Variant = variant
+	}
+
+	if c.FormValue("players") == "3" {
+		if err := tictactoe.CheckThreePlayers(game.Variant, &game.Board); err != nil {
+			return nil, err
+		}
+		game.ThreePlayers = true
+	}
+	return game, nil
+}
+
+// Creates a game that starts from the position set up in the setup editor,
+// one piece per cell in board order and the player that moves next
+func setupGameFromForm(c echo.Context) (*tictactoe.Game, error) {
+	game, err := gameFromForm(c)
+	if err != nil {
+		return nil, err
+	}
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, err
+	}
+	toMove := 0b01
+	if c.FormValue("to_move") == "O" {
+		toMove = 0b10
+	}
+	position, err := tictactoe.NewPosition(&game.Board, form["cell"], toMove)
+	if err != nil {
+		return nil, err
+	}
+	if err := game.SetStart(position); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// Reads the optional width, height and win length of a new game's board,
+// defaulting to classic 3x3 tic-tac-toe
+func boardFromForm(c echo.Context) (*tictactoe.Board, error) {
+	dimension := func(name string, fallback int) (int, error) {
+		value := c.FormValue(name)
+		if value == "" {
+			return fallback, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", name, value)
+		}
+		return n, nil
+	}
+
+	width, err := dimension("width", 3)
+	if err != nil {
+		return nil, err
+	}
+	height, err := dimension("height", width)
+	if err != nil {
+		return nil, err
+	}
+	winLength, err := dimension("win", min(width, height, 3))
+	if err != nil {
+		return nil, err
+	}
+
+	return tictactoe.NewBoardWithSize(width, height, winLength)
+}
+
 type SingleLineWriter struct {
 	Writer io.Writer
 	buffer bytes.Buffer
@@ -67,4 +176,4 @@ func sendSse(eventName string, msg string, c echo.Context) {
 	fmt.Fprintf(w, "event: %s\n", eventName)
 	fmt.Fprintf(w, "data: %s\n\n", msg)
 	c.Response().Flush()
-}
\ No newline at end of file
+}
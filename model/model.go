@@ -3,22 +3,35 @@ package model
 import (
 	"jay/tictactoe/internal/events"
 	tictactoe "jay/tictactoe/pkg"
+	"jay/tictactoe/pkg/engine"
+	"time"
 )
 
 type ServerGame struct {
 	*tictactoe.Game
 	Listeners map[tictactoe.ParticipantId]map[chan<- *GamePlayEvent]struct{}
+	Bot       *BotSeat    // Computer opponent that takes the second seat, nil for human games
+	Reconnect *time.Timer // Abandons the game while it is paused for its players
+}
+
+type BotSeat struct {
+	*engine.Bot
+	Participant *tictactoe.Participant // nil until the bot has joined
+	Delay       time.Duration          // Thinking time before each move
 }
 
 type GamePlayEvent struct {
 	GameId    tictactoe.GameId
 	Info      string
 	EventType events.GamePlayEventType
+	Move      *tictactoe.Move     // Set for MovePlayed events
+	State     tictactoe.GameState // Set for StateChanged events
 }
 
 type GameStatusEvent struct {
 	GameId tictactoe.GameId
 	Info   string
+	State  tictactoe.GameState
 }
 
 type GamePage struct {
@@ -35,4 +48,69 @@ type GameHistoryControls struct {
 	CanGoForward  bool
 	CanGoBack     bool
 	AtCurrent     bool
+	Ply           int
+	Plies         int
+	Move          *tictactoe.Move // Move that led to the displayed board, nil at the start
+	Decided       bool            // The displayed board already has a result, play can't continue from it
+}
+
+// NewGameHistoryControls derives the history controls from the game's move log
+// for the board `offset` moves before the current one. The offset is clamped to
+// the available history
+func NewGameHistoryControls(game *tictactoe.Game, offset int) *GameHistoryControls {
+	plies := len(game.Moves)
+	offset = max(min(offset, 0), -plies)
+	ply := plies + offset
+	controls := &GameHistoryControls{
+		Id:            game.Id,
+		BackOffset:    offset - 1,
+		Offset:        offset,
+		ForwardOffset: offset + 1,
+		CanGoBack:     ply > 0,
+		CanGoForward:  offset < 0,
+		AtCurrent:     offset == 0,
+		Ply:           ply,
+		Plies:         plies,
+	}
+	if ply > 0 {
+		controls.Move = &game.Moves[ply-1]
+	}
+	if o := game.Outcome; o != nil && o.Move != nil {
+		controls.Decided = ply >= o.Move.Ply
+	}
+	return controls
+}
+
+// GameAnalysis labels every empty cell of a game's current position
+type GameAnalysis struct {
+	GameId tictactoe.GameId      `json:"gameId"`
+	Player string                `json:"player"` // Symbol of the player to move
+	Width  int                   `json:"width"`
+	Height int                   `json:"height"`
+	Best   *engine.CellAnalysis  `json:"best"`
+	Cells  []engine.CellAnalysis `json:"cells"`
+}
+
+func NewGameAnalysis(game *tictactoe.Game, player int, cells []engine.CellAnalysis) *GameAnalysis {
+	analysis := &GameAnalysis{
+		GameId: game.Id,
+		Player: tictactoe.PlayerSymbol(player),
+		Width:  game.Board.Width(),
+		Height: game.Board.Height(),
+		Cells:  cells,
+	}
+	if best, ok := engine.Best(cells); ok {
+		analysis.Best = &best
+	}
+	return analysis
+}
+
+// Cell returns the analysis of a cell or nil if it has already been played
+func (a *GameAnalysis) Cell(index int) *engine.CellAnalysis {
+	for i := range a.Cells {
+		if a.Cells[i].Cell == index {
+			return &a.Cells[i]
+		}
+	}
+	return nil
 }
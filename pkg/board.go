@@ -3,64 +3,257 @@ package tictactoe
 import (
 	"errors"
 	"fmt"
+	"math/bits"
+	"strings"
 )
 
+// MaxBoardCells is the largest number of cells a Board can hold (e.g. 16x16)
+const MaxBoardCells = 256
+
+const boardWords = MaxBoardCells / 64
+
+// Number of players that can own cells on a board, the third one uses the
+// 0b11 cell value
+const boardPlayers = 3
+
 type Cell struct {
 	Symbol string
 	Index  uint
 	// GameId GameId
 }
 
+// bitboard has one bit per cell of a board
+type bitboard [boardWords]uint64
+
+func (bb *bitboard) set(index int) {
+	bb[index/64] |= 1 << (index % 64)
+}
+
+func (bb *bitboard) has(index int) bool {
+	return bb[index/64]&(1<<(index%64)) != 0
+}
+
+func (bb *bitboard) intersects(mask *bitboard) bool {
+	for i := range bb {
+		if bb[i]&mask[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (bb *bitboard) and(mask *bitboard) bitboard {
+	var result bitboard
+	for i := range bb {
+		result[i] = bb[i] & mask[i]
+	}
+	return result
+}
+
+func (bb *bitboard) count() int {
+	n := 0
+	for _, word := range bb {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Board keeps one bitmask per player in fixed size arrays so that it can
+// still be copied by value (e.g. into a game's history). Cell values are the
+// 2-bit player numbers: 0b00 empty, 0b01 X, 0b10 O and 0b11 Y
 type Board struct {
-	value int
+	width     int
+	height    int
+	winLength int
+	players   [boardPlayers]bitboard
+	lines     *winTable
 }
 
+// NewBoardWithValue returns a classic 3x3 board seeded with 2 bits per cell
 func NewBoardWithValue(val int) *Board {
-	return &Board{
-		value: val,
+	b := NewBoard()
+	for i := 0; i < b.Size(); i++ {
+		if player := (val >> (i * 2)) & 0b11; player != 0b00 {
+			b.setCell(i, player)
+		}
 	}
+	return b
 }
 
+// NewBoard returns an empty classic 3x3 board with 3 in a row to win
 func NewBoard() *Board {
+	b, _ := NewBoardWithSize(3, 3, 3)
+	return b
+}
+
+// NewBoardWithSize returns an empty width x height board where winLength in a
+// row wins
+func NewBoardWithSize(width int, height int, winLength int) (*Board, error) {
+	if width < 1 || height < 1 || width*height > MaxBoardCells {
+		return nil, fmt.Errorf("invalid board size %dx%d", width, height)
+	}
+	if winLength < 1 || (winLength > width && winLength > height) {
+		return nil, fmt.Errorf("invalid win length %d for a %dx%d board", winLength, width, height)
+	}
 	return &Board{
-		value: 0,
+		width:     width,
+		height:    height,
+		winLength: winLength,
+		lines:     winTableFor(width, height, winLength),
+	}, nil
+}
+
+func (b *Board) Width() int {
+	return b.width
+}
+
+func (b *Board) Height() int {
+	return b.height
+}
+
+func (b *Board) WinLength() int {
+	return b.winLength
+}
+
+// Size returns the number of cells on the board
+func (b *Board) Size() int {
+	return b.width * b.height
+}
+
+// empty returns a board with the same dimensions and no moves played
+func (b *Board) empty() Board {
+	return Board{
+		width:     b.width,
+		height:    b.height,
+		winLength: b.winLength,
+		lines:     b.lines,
 	}
 }
 
 func (b *Board) setCell(index int, player int) error {
-	if player > 0b10 {
+	if player < 0b01 || player > boardPlayers {
 		return errors.New("invalid player")
 	}
-	val := int(player) << (index * 2)
-	b.value |= int(val)
+	if index < 0 || index >= b.Size() {
+		return errors.New("invalid cell")
+	}
+	b.players[player-1].set(index)
 	return nil
 }
 
+// WithMove returns a copy of the board with the player's move played on it
+func (b Board) WithMove(index int, player int) (Board, error) {
+	if b.GetCell(index) != 0b00 {
+		return b, errors.New("cell not empty")
+	}
+	err := b.setCell(index, player)
+	return b, err
+}
+
+// NextPlayer guesses the player to move from the number of pieces on the
+// board, assuming X moved first
+func (b *Board) NextPlayer() int {
+	if b.players[0].count() > b.players[1].count() {
+		return 0b10
+	}
+	return 0b01
+}
+
+// Opponent returns the player that moves after the given one in a two player
+// game
+func Opponent(player int) int {
+	return 3 - player
+}
+
 func (b *Board) GetCell(index int) int {
-	return (b.value >> (index * 2)) & 0b11
+	if index < 0 || index >= b.Size() {
+		return 0b00
+	}
+	for p := range b.players {
+		if b.players[p].has(index) {
+			return p + 1
+		}
+	}
+	return 0b00
+}
+
+// occupied returns a mask of every cell that has been played
+func (b *Board) occupied() bitboard {
+	var mask bitboard
+	for p := range b.players {
+		for i := range mask {
+			mask[i] |= b.players[p][i]
+		}
+	}
+	return mask
+}
+
+// MoveCount returns the number of cells that have been played
+func (b *Board) MoveCount() int {
+	occupied := b.occupied()
+	return occupied.count()
+}
+
+func (b *Board) Full() bool {
+	return b.MoveCount() == b.Size()
 }
 
 func (b *Board) Bin() string {
-	return fmt.Sprintf("%018b", b.value)
+	var sb strings.Builder
+	for i := b.Size() - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "%02b", b.GetCell(i))
+	}
+	return sb.String()
 }
 
 func (b *Board) Symbol(index uint) string {
-	switch b.GetCell(int(index)) {
+	return PlayerSymbol(b.GetCell(int(index)))
+}
+
+// PlayerSymbol returns the symbol a player's cell value is drawn with
+func PlayerSymbol(player int) string {
+	switch player {
 	case 0b00:
 		return ""
 	case 0b01:
 		return "X"
 	case 0b10:
 		return "O"
+	case 0b11:
+		return "Y"
 	}
 	return "?"
 }
 
+// ParsePiece returns the cell value of a piece drawn with the given symbol
+func ParsePiece(symbol string) (int, error) {
+	if piece := symbolPlayer(symbol); piece > 0 {
+		return piece, nil
+	}
+	return 0, fmt.Errorf("invalid piece %q", symbol)
+}
+
+func (b *Board) Cells() <-chan *Cell {
+	ch := make(chan *Cell)
+	go func() {
+		for i := 0; i < b.Size(); i++ {
+			cell := &Cell{
+				Symbol: b.Symbol(uint(i)),
+				Index:  uint(i),
+				// GameId: g.Id,
+			}
+			ch <- cell
+		}
+		close(ch)
+	}()
+	return ch
+}
+
 func (b *Board) String() string {
 	val := ""
-	for r := 0; r < 3; r++ {
-		for c := 0; c < 3; c++ {
-			s := b.Symbol(uint(r*3 + c))
+	for r := 0; r < b.height; r++ {
+		for c := 0; c < b.width; c++ {
+			s := b.Symbol(uint(r*b.width + c))
 			if s == "" {
 				s = "?"
 			}
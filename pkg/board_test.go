@@ -0,0 +1,146 @@
+package tictactoe
+
+import "testing"
+
+// scanWinningLine is the plain cell by cell search boards used before they
+// kept bitmasks, the bitboard code has to agree with it
+func scanWinningLine(b *Board) *Line {
+	k := b.WinLength()
+	for i := 0; i < b.Size(); i++ {
+		player := b.GetCell(i)
+		if player == 0b00 {
+			continue
+		}
+		row, col := i/b.Width(), i%b.Width()
+		for dir, step := range lineDirections {
+			endRow, endCol := row+step[0]*(k-1), col+step[1]*(k-1)
+			if endRow < 0 || endRow >= b.Height() || endCol < 0 || endCol >= b.Width() {
+				continue
+			}
+			cells := []int{i}
+			for n := 1; n < k; n++ {
+				cell := (row+step[0]*n)*b.Width() + col + step[1]*n
+				if b.GetCell(cell) != player {
+					break
+				}
+				cells = append(cells, cell)
+			}
+			if len(cells) == k {
+				return &Line{Cells: cells, Direction: Direction(dir)}
+			}
+		}
+	}
+	return nil
+}
+
+func scanFull(b *Board) bool {
+	for i := 0; i < b.Size(); i++ {
+		if b.GetCell(i) == 0b00 {
+			return false
+		}
+	}
+	return true
+}
+
+// reachable calls visit with every position that can come up in a game on an
+// empty board, with the cell of the move that led to it
+func reachable(board Board, player int, last int, seen map[string]bool, visit func(b *Board, last int)) {
+	key := board.Key()
+	if seen[key] {
+		return
+	}
+	seen[key] = true
+	visit(&board, last)
+	if scanWinningLine(&board) != nil || scanFull(&board) {
+		return
+	}
+	for i := 0; i < board.Size(); i++ {
+		if next, err := board.WithMove(i, player); err == nil {
+			reachable(next, Opponent(player), i, seen, visit)
+		}
+	}
+}
+
+func TestBoardMatchesCellScan(t *testing.T) {
+	seen := map[string]bool{}
+	reachable(*NewBoard(), 0b01, -1, seen, func(b *Board, last int) {
+		want, got := scanWinningLine(b), b.WinningLine()
+		if (want == nil) != (got == nil) {
+			t.Fatalf("%s: WinningLine = %v, cell scan found %v", b.Key(), got, want)
+		}
+		if got != nil {
+			owner := b.GetCell(got.Cells[0])
+			for _, cell := range got.Cells {
+				if b.GetCell(cell) != owner {
+					t.Fatalf("%s: line %v is not owned by one player", b.Key(), got.Cells)
+				}
+			}
+			if owner != b.GetCell(want.Cells[0]) {
+				t.Fatalf("%s: WinningLine is %s's, cell scan found %s's", b.Key(), PlayerSymbol(owner), b.Symbol(uint(want.Cells[0])))
+			}
+		}
+		if last >= 0 && (b.WinningLineThrough(last) == nil) != (got == nil) {
+			// Play stops at the first line, so it runs through the last move
+			t.Fatalf("%s: WinningLineThrough(%d) disagrees with WinningLine", b.Key(), last)
+		}
+		if b.Full() != scanFull(b) {
+			t.Fatalf("%s: Full = %v", b.Key(), b.Full())
+		}
+	})
+	if len(seen) != 5478 {
+		t.Errorf("visited %d positions, a 3x3 board has 5478 reachable ones", len(seen))
+	}
+}
+
+// Moves of a game on a 3x3 board that fills every cell without a line
+var fullGame = []int{4, 0, 8, 2, 1, 7, 6, 3, 5}
+
+func BenchmarkBoardMove(b *testing.B) {
+	board := NewBoard()
+	for i := 0; i < b.N; i++ {
+		board.WithMove(4, 0b01)
+	}
+}
+
+func BenchmarkBoardWinningLine(b *testing.B) {
+	// Three rows of alternating pairs, lots of pieces but no five in a row
+	board, _ := NewBoardWithSize(16, 16, 5)
+	for cell := 0; cell < 48; cell++ {
+		board.setCell(cell, (cell%16/2+cell/16)%2+1)
+	}
+	b.Run("bitboard", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			board.WinningLine()
+		}
+	})
+	b.Run("cell scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			scanWinningLine(board)
+		}
+	})
+}
+
+func BenchmarkBoardFullGame(b *testing.B) {
+	b.Run("bitboard", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			board := *NewBoard()
+			for n, cell := range fullGame {
+				board, _ = board.WithMove(cell, n%2+1)
+				if board.WinningLineThrough(cell) != nil || board.Full() {
+					break
+				}
+			}
+		}
+	})
+	b.Run("cell scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			board := *NewBoard()
+			for n, cell := range fullGame {
+				board, _ = board.WithMove(cell, n%2+1)
+				if scanWinningLine(&board) != nil || scanFull(&board) {
+					break
+				}
+			}
+		}
+	})
+}
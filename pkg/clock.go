@@ -0,0 +1,168 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock tells the time. Timed games read it through their Clocks so that
+// tests can move time forward by hand instead of waiting
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+// TimeControl is the time every player starts with and the time added to
+// their clock after each of their moves
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+}
+
+// String writes the time control the way it is usually written: base minutes
+// plus increment seconds, e.g. "5+3"
+func (tc TimeControl) String() string {
+	return strconv.FormatFloat(tc.Base.Minutes(), 'f', -1, 64) + "+" + strconv.FormatFloat(tc.Increment.Seconds(), 'f', -1, 64)
+}
+
+// ParseTimeControl reads a time control such as "5+3" (5 minutes with 3
+// seconds per move) or "1" (1 minute without increment)
+func ParseTimeControl(s string) (TimeControl, error) {
+	base, increment, _ := strings.Cut(strings.TrimSpace(s), "+")
+	minutes, err := strconv.ParseFloat(base, 64)
+	if err != nil || minutes <= 0 {
+		return TimeControl{}, fmt.Errorf("invalid time control %q", s)
+	}
+	seconds := 0.0
+	if increment != "" {
+		if seconds, err = strconv.ParseFloat(increment, 64); err != nil || seconds < 0 {
+			return TimeControl{}, fmt.Errorf("invalid time control %q", s)
+		}
+	}
+	return TimeControl{
+		Base:      time.Duration(minutes * float64(time.Minute)),
+		Increment: time.Duration(seconds * float64(time.Second)),
+	}, nil
+}
+
+// Clocks keeps the time left of every player of a timed game. Only the clock
+// of the player to move runs, and only while the game is in progress
+type Clocks struct {
+	Control TimeControl
+	// Time left by player value (index 0 is player 1), not counting the turn
+	// that is being played
+	Remaining [boardPlayers]time.Duration
+	// When the running clock was started, zero while the clocks are stopped
+	Started time.Time
+	Clock   Clock
+}
+
+// NewClocks gives every player the base time of the control, a nil clock
+// uses the SystemClock
+func NewClocks(control TimeControl, clock Clock) *Clocks {
+	if clock == nil {
+		clock = SystemClock
+	}
+	c := &Clocks{Control: control, Clock: clock}
+	for i := range c.Remaining {
+		c.Remaining[i] = control.Base
+	}
+	return c
+}
+
+func (c *Clocks) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+// SetTimeControl makes a game that has not started yet a timed game
+func (g *Game) SetTimeControl(control TimeControl, clock Clock) error {
+	if g.Started() {
+		return errors.New("The game has already started")
+	}
+	if control.Base <= 0 || control.Increment < 0 {
+		return fmt.Errorf("Invalid time control %s", control)
+	}
+	g.Clocks = NewClocks(control, clock)
+	return nil
+}
+
+// now is the time according to the game's clock
+func (g *Game) now() time.Time {
+	if g.Clocks != nil {
+		return g.Clocks.now()
+	}
+	return time.Now()
+}
+
+// TimeLeft returns the time left on a player's clock, including the turn
+// they are playing. Untimed games have no time left
+func (g *Game) TimeLeft(player int) time.Duration {
+	c := g.Clocks
+	if c == nil || player < 0b01 || player > boardPlayers {
+		return 0
+	}
+	left := c.Remaining[player-1]
+	if !c.Started.IsZero() && player == g.CurrentPlayerValue() {
+		left -= c.now().Sub(c.Started)
+	}
+	return max(left, 0)
+}
+
+// ClockRunning reports whether the player's clock is ticking
+func (g *Game) ClockRunning(player int) bool {
+	return g.Clocks != nil && !g.Clocks.Started.IsZero() && player == g.CurrentPlayerValue()
+}
+
+// CheckTime forfeits the game of the player to move once their time is up,
+// also while the game is paused for them, and reports whether it did
+func (g *Game) CheckTime() bool {
+	if g.Clocks == nil || g.CurrentPlayer == nil || !g.ClockRunning(g.CurrentPlayerValue()) {
+		return false
+	}
+	if g.TimeLeft(g.CurrentPlayerValue()) > 0 {
+		return false
+	}
+	return g.TimeOut(g.CurrentPlayer) == nil
+}
+
+// startClock starts the clock of the player to move
+func (g *Game) startClock() {
+	if c := g.Clocks; c != nil && c.Started.IsZero() {
+		c.Started = c.now()
+	}
+}
+
+// stopClock charges the turn so far to the player to move
+func (g *Game) stopClock() {
+	c := g.Clocks
+	if c == nil || c.Started.IsZero() {
+		return
+	}
+	if player := g.CurrentPlayerValue(); player != 0 {
+		c.Remaining[player-1] = g.TimeLeft(player)
+	}
+	c.Started = time.Time{}
+}
+
+// pressClock ends the mover's turn on the clock and adds their increment
+func (g *Game) pressClock(player int) {
+	if g.Clocks == nil {
+		return
+	}
+	g.stopClock()
+	g.Clocks.Remaining[player-1] += g.Clocks.Control.Increment
+}
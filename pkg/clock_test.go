@@ -0,0 +1,124 @@
+package tictactoe
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when a test moves it
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// timedGame starts a 1+2 game between alice and bob
+func timedGame(t *testing.T) (*Game, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)}
+	game := NewGame(1)
+	if err := game.SetTimeControl(TimeControl{Base: time.Minute, Increment: 2 * time.Second}, clock); err != nil {
+		t.Fatal(err)
+	}
+	game.Join("alice", "alice")
+	game.Join("bob", "bob")
+	if game.State != InProgress {
+		t.Fatalf("game is %s after both players joined", game.State)
+	}
+	return game, clock
+}
+
+func TestClockAddsIncrementAfterMove(t *testing.T) {
+	game, clock := timedGame(t)
+	clock.advance(10 * time.Second)
+	if left := game.TimeLeft(0b01); left != 50*time.Second {
+		t.Errorf("X has %s left while thinking, want 50s", left)
+	}
+	if err := game.PlayMove(0b01, 4); err != nil {
+		t.Fatal(err)
+	}
+	if left := game.TimeLeft(0b01); left != 52*time.Second {
+		t.Errorf("X has %s left after moving, want 52s", left)
+	}
+	if game.ClockRunning(0b01) || !game.ClockRunning(0b10) {
+		t.Error("the clock didn't pass to O")
+	}
+	clock.advance(5 * time.Second)
+	if left := game.TimeLeft(0b01); left != 52*time.Second {
+		t.Errorf("X has %s left on O's turn, want 52s", left)
+	}
+}
+
+func TestClockForfeitsWithoutMove(t *testing.T) {
+	game, clock := timedGame(t)
+	clock.advance(59 * time.Second)
+	if game.CheckTime() {
+		t.Fatal("X flagged with a second left")
+	}
+	clock.advance(time.Second)
+	if !game.CheckTime() {
+		t.Fatal("X didn't flag once their time was up")
+	}
+	if game.State != Finished || game.Outcome.Kind != TimeoutForfeit || game.Outcome.Loser != game.Player1 {
+		t.Errorf("flagging ended the game %s with %v", game.State, game.Outcome)
+	}
+}
+
+func TestClockStopsWhilePaused(t *testing.T) {
+	game, clock := timedGame(t)
+	clock.advance(10 * time.Second)
+	if err := game.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(time.Hour)
+	if game.CheckTime() {
+		t.Fatal("X flagged while the game was paused")
+	}
+	if left := game.TimeLeft(0b01); left != 50*time.Second {
+		t.Errorf("X has %s left after a pause, want 50s", left)
+	}
+	if err := game.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(10 * time.Second)
+	if left := game.TimeLeft(0b01); left != 40*time.Second {
+		t.Errorf("X has %s left after resuming, want 40s", left)
+	}
+}
+
+func TestClockKeepsRunningWhilePausedForDisconnect(t *testing.T) {
+	game, clock := timedGame(t)
+	if err := game.PauseFor(game.Player2); err != nil || game.State != InProgress {
+		t.Fatalf("O leaving on X's turn left the game %s, %v", game.State, err)
+	}
+	if err := game.PauseFor(game.Player1); err != nil || game.State != Paused {
+		t.Fatalf("X leaving on their turn left the game %s, %v", game.State, err)
+	}
+	clock.advance(30 * time.Second)
+	if left := game.TimeLeft(0b01); left != 30*time.Second {
+		t.Errorf("X has %s left after being away 30s, want 30s", left)
+	}
+	clock.advance(30 * time.Second)
+	if !game.CheckTime() || game.State != Finished || game.Outcome.Loser != game.Player1 {
+		t.Errorf("X didn't flag while away, game is %s with %v", game.State, game.Outcome)
+	}
+}
+
+func TestTimedGamesDontTakeBack(t *testing.T) {
+	game, clock := timedGame(t)
+	clock.advance(10 * time.Second)
+	if err := game.PlayMove(0b01, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.RequestTakeback(game.Player1); err == nil {
+		t.Error("X asked to take back a move of a timed game")
+	}
+	if len(game.Moves) != 1 || game.TakebackRequest != nil {
+		t.Errorf("the takeback left %d moves and request %v", len(game.Moves), game.TakebackRequest)
+	}
+}
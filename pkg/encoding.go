@@ -0,0 +1,1088 @@
+package tictactoe
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Every binary record starts with the version of its format and games in
+// JSON carry one as well. Each kind of record has its own version, bumped
+// only when its own format changes, and decoders read every version up to
+// the current one. Up to version 9 all records shared a single version,
+// which is where each of them continues from. Version 2 is the first with
+// game states, version 3 the first with variants and version 4 the first
+// with pieces on moves
+const (
+	BoardVersion       = 9
+	ParticipantVersion = 9
+	MoveVersion        = 9
+	GameVersion        = 9
+	GameJSONVersion    = 9
+)
+
+var errEncodingVersion = errors.New("unsupported encoding version")
+
+// Board
+
+func (b Board) MarshalText() ([]byte, error) {
+	return []byte(b.Key()), nil
+}
+
+// UnmarshalText parses a board Key such as "3x3/3:X.O/.X./..O"
+func (b *Board) UnmarshalText(text []byte) error {
+	var width, height, winLength int
+	size, cells, found := strings.Cut(string(text), ":")
+	if !found {
+		return fmt.Errorf("invalid board %q", text)
+	}
+	if _, err := fmt.Sscanf(size, "%dx%d/%d", &width, &height, &winLength); err != nil {
+		return fmt.Errorf("invalid board size %q", size)
+	}
+	board, err := NewBoardWithSize(width, height, winLength)
+	if err != nil {
+		return err
+	}
+
+	cells = strings.ReplaceAll(cells, "/", "")
+	if len(cells) != board.Size() {
+		return fmt.Errorf("expected %d cells, got %d", board.Size(), len(cells))
+	}
+	for i, symbol := range cells {
+		if symbol == '.' {
+			continue
+		}
+		player := symbolPlayer(string(symbol))
+		if err := board.setCell(i, player); err != nil {
+			return fmt.Errorf("cell %d: %w", i, err)
+		}
+	}
+	*b = *board
+	return nil
+}
+
+func symbolPlayer(symbol string) int {
+	for player := 0b01; player <= boardPlayers; player++ {
+		if PlayerSymbol(player) == symbol {
+			return player
+		}
+	}
+	return -1
+}
+
+func (b Board) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.byte(BoardVersion)
+	e.board(&b)
+	return e.buf, nil
+}
+
+func (b *Board) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	d.version(BoardVersion)
+	board := d.board()
+	if err := d.done(); err != nil {
+		return err
+	}
+	*b = board
+	return nil
+}
+
+// Participant
+
+func (p Participant) MarshalText() ([]byte, error) {
+	values := url.Values{
+		"id":        {string(p.Id)},
+		"name":      {p.Name},
+		"player":    {strconv.FormatBool(p.Player)},
+		"connected": {strconv.FormatBool(p.Connected)},
+		"bot":       {strconv.FormatBool(p.Bot)},
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (p *Participant) UnmarshalText(text []byte) error {
+	values, err := url.ParseQuery(string(text))
+	if err != nil {
+		return err
+	}
+	*p = Participant{
+		Id:        ParticipantId(values.Get("id")),
+		Name:      values.Get("name"),
+		Player:    values.Get("player") == "true",
+		Connected: values.Get("connected") == "true",
+		Bot:       values.Get("bot") == "true",
+	}
+	return nil
+}
+
+// Participants are written as objects in JSON rather than in their text form
+func (p Participant) MarshalJSON() ([]byte, error) {
+	type participant Participant
+	return json.Marshal(participant(p))
+}
+
+func (p *Participant) UnmarshalJSON(data []byte) error {
+	type participant Participant
+	return json.Unmarshal(data, (*participant)(p))
+}
+
+func (p Participant) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.byte(ParticipantVersion)
+	e.participant(&p)
+	return e.buf, nil
+}
+
+func (p *Participant) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	d.version(ParticipantVersion)
+	participant := d.participant()
+	if err := d.done(); err != nil {
+		return err
+	}
+	*p = *participant
+	return nil
+}
+
+// Move log
+
+// MarshalText writes a move as "<ply> <player> <cell> <time>", e.g.
+// "1 X 4 2024-08-01T12:00:00Z". A piece other than the player's own follows
+// the player as in "X=O"
+func (m Move) MarshalText() ([]byte, error) {
+	timestamp := "-"
+	if !m.Time.IsZero() {
+		timestamp = m.Time.Format(time.RFC3339Nano)
+	}
+	player := PlayerSymbol(m.Player)
+	if m.Piece != m.Player {
+		player += "=" + m.Symbol()
+	}
+	return []byte(fmt.Sprintf("%d %s %d %s", m.Ply, player, m.Cell, timestamp)), nil
+}
+
+func (m *Move) UnmarshalText(text []byte) error {
+	var symbol, timestamp string
+	var move Move
+	if _, err := fmt.Sscanf(string(text), "%d %s %d %s", &move.Ply, &symbol, &move.Cell, &timestamp); err != nil {
+		return fmt.Errorf("invalid move %q", text)
+	}
+	player, piece, found := strings.Cut(symbol, "=")
+	if !found {
+		piece = player
+	}
+	if move.Player = symbolPlayer(player); move.Player < 0 {
+		return fmt.Errorf("invalid player %q", player)
+	}
+	if move.Piece = symbolPlayer(piece); move.Piece < 0 {
+		return fmt.Errorf("invalid piece %q", piece)
+	}
+	if timestamp != "-" {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return err
+		}
+		move.Time = t
+	}
+	*m = move
+	return nil
+}
+
+// Moves are written as objects in JSON rather than in their text form
+func (m Move) MarshalJSON() ([]byte, error) {
+	type move Move
+	return json.Marshal(move(m))
+}
+
+func (m *Move) UnmarshalJSON(data []byte) error {
+	type move Move
+	if err := json.Unmarshal(data, (*move)(m)); err != nil {
+		return err
+	}
+	if m.Piece == 0 {
+		// Written before moves had pieces
+		m.Piece = m.Player
+	}
+	return nil
+}
+
+func (m Move) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.byte(MoveVersion)
+	e.move(&m)
+	return e.buf, nil
+}
+
+func (m *Move) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	version := d.version(MoveVersion)
+	move := d.move(version >= 4)
+	if err := d.done(); err != nil {
+		return err
+	}
+	*m = move
+	return nil
+}
+
+// MarshalText writes one move per line
+func (l MoveLog) MarshalText() ([]byte, error) {
+	lines := make([]string, len(l))
+	for i, move := range l {
+		text, _ := move.MarshalText()
+		lines[i] = string(text)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func (l *MoveLog) UnmarshalText(text []byte) error {
+	var log MoveLog
+	for _, line := range strings.Split(strings.TrimSpace(string(text)), "\n") {
+		if line == "" {
+			continue
+		}
+		var move Move
+		if err := move.UnmarshalText([]byte(line)); err != nil {
+			return err
+		}
+		log = append(log, move)
+	}
+	*l = log
+	return nil
+}
+
+func (l MoveLog) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Move(l))
+}
+
+func (l *MoveLog) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*[]Move)(l))
+}
+
+func (l MoveLog) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.byte(MoveVersion)
+	e.moves(l)
+	return e.buf, nil
+}
+
+func (l *MoveLog) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	version := d.version(MoveVersion)
+	moves := d.moves(version >= 4)
+	if err := d.done(); err != nil {
+		return err
+	}
+	*l = moves
+	return nil
+}
+
+// Outcome kinds and directions
+
+func (k OutcomeKind) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(k.String())), nil
+}
+
+func (k *OutcomeKind) UnmarshalText(text []byte) error {
+	for kind := Win; kind <= Abandonment; kind++ {
+		if strings.ToLower(kind.String()) == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome %q", text)
+}
+
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Direction) UnmarshalText(text []byte) error {
+	for dir := Horizontal; dir <= Depth; dir++ {
+		if dir.String() == string(text) {
+			*d = dir
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown direction %q", text)
+}
+
+func (s GameState) MarshalText() ([]byte, error) {
+	return []byte(stateName(s)), nil
+}
+
+func (s *GameState) UnmarshalText(text []byte) error {
+	for state := Lobby; state <= Abandoned; state++ {
+		if stateName(state) == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown game state %q", text)
+}
+
+func (v Variant) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(v.String())), nil
+}
+
+func (v *Variant) UnmarshalText(text []byte) error {
+	variant, err := ParseVariant(string(text))
+	if err != nil {
+		return err
+	}
+	*v = variant
+	return nil
+}
+
+func (tc TimeControl) MarshalText() ([]byte, error) {
+	return []byte(tc.String()), nil
+}
+
+func (tc *TimeControl) UnmarshalText(text []byte) error {
+	control, err := ParseTimeControl(string(text))
+	if err != nil {
+		return err
+	}
+	*tc = control
+	return nil
+}
+
+// stateName turns "Waiting for opponent" into "waiting-for-opponent"
+func stateName(s GameState) string {
+	return strings.ReplaceAll(strings.ToLower(s.String()), " ", "-")
+}
+
+// Game
+
+// MarshalText writes the game in text notation, see Notation. Participants
+// and timestamps are not part of it
+func (g *Game) MarshalText() ([]byte, error) {
+	return []byte(g.Notation()), nil
+}
+
+func (g *Game) UnmarshalText(text []byte) error {
+	game, err := ParseNotation(string(text))
+	if err != nil {
+		return err
+	}
+	*g = *game
+	return nil
+}
+
+type gameJSON struct {
+	Version       int             `json:"version"`
+	Id            GameId          `json:"id"`
+	Board         Board           `json:"board"`
+	EarlyDraws    bool            `json:"earlyDraws"`
+	State         GameState       `json:"state"`
+	Variant       Variant         `json:"variant"`
+	ThreePlayers  bool            `json:"threePlayers,omitempty"`
+	Participants  []*Participant  `json:"participants"`
+	Player1       *ParticipantId  `json:"player1"`
+	Player2       *ParticipantId  `json:"player2"`
+	Player3       *ParticipantId  `json:"player3,omitempty"`
+	CurrentPlayer *ParticipantId  `json:"currentPlayer"`
+	Moves         MoveLog         `json:"moves"`
+	Outcome       *outcomeJSON    `json:"outcome"`
+	Clocks        *clocksJSON     `json:"clocks,omitempty"`
+	DrawOffers    []ParticipantId `json:"drawOffers,omitempty"`
+	Takeback      *ParticipantId  `json:"takebackRequest,omitempty"`
+	UnlimitedUndo bool            `json:"unlimitedUndo,omitempty"`
+	Start         *Position       `json:"start,omitempty"`
+}
+
+type clocksJSON struct {
+	Control   TimeControl     `json:"control"`
+	Remaining []time.Duration `json:"remaining"` // Nanoseconds per player
+	Started   *time.Time      `json:"started,omitempty"`
+}
+
+type outcomeJSON struct {
+	Kind   OutcomeKind    `json:"kind"`
+	Winner *ParticipantId `json:"winner"`
+	Loser  *ParticipantId `json:"loser"`
+	Reason string         `json:"reason"`
+	Ply    int            `json:"ply,omitempty"` // Ply of the deciding move
+	Line   *Line          `json:"line,omitempty"`
+}
+
+func participantRef(p *Participant) *ParticipantId {
+	if p == nil {
+		return nil
+	}
+	return &p.Id
+}
+
+func (g *Game) MarshalJSON() ([]byte, error) {
+	data := gameJSON{
+		Version:       GameJSONVersion,
+		Id:            g.Id,
+		Board:         g.Board,
+		EarlyDraws:    g.EarlyDraws,
+		State:         g.State,
+		Variant:       g.Variant,
+		ThreePlayers:  g.ThreePlayers,
+		Participants:  g.allParticipants(),
+		Player1:       participantRef(g.Player1),
+		Player2:       participantRef(g.Player2),
+		Player3:       participantRef(g.Player3),
+		CurrentPlayer: participantRef(g.CurrentPlayer),
+		Moves:         g.Moves,
+		Takeback:      participantRef(g.TakebackRequest),
+		UnlimitedUndo: g.UnlimitedUndo,
+		Start:         g.Start,
+	}
+	if o := g.Outcome; o != nil {
+		data.Outcome = &outcomeJSON{
+			Kind:   o.Kind,
+			Winner: participantRef(o.Winner),
+			Loser:  participantRef(o.Loser),
+			Reason: o.Reason,
+			Line:   o.Line,
+		}
+		if o.Move != nil {
+			data.Outcome.Ply = o.Move.Ply
+		}
+	}
+	for _, p := range g.DrawOffers {
+		data.DrawOffers = append(data.DrawOffers, p.Id)
+	}
+	if c := g.Clocks; c != nil {
+		data.Clocks = &clocksJSON{
+			Control:   c.Control,
+			Remaining: c.Remaining[:g.PlayerCount()],
+		}
+		if !c.Started.IsZero() {
+			data.Clocks.Started = &c.Started
+		}
+	}
+	return json.Marshal(data)
+}
+
+func (g *Game) UnmarshalJSON(data []byte) error {
+	// Games written before they had a state leave it unknown
+	decoded := gameJSON{State: -1}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Version < 1 || decoded.Version > GameJSONVersion {
+		return errEncodingVersion
+	}
+
+	game, err := newDecodedGame(decoded.Id, decoded.Board, decoded.Participants)
+	if err != nil {
+		return err
+	}
+	game.EarlyDraws = decoded.EarlyDraws
+	game.State = decoded.State
+	game.Variant = decoded.Variant
+	game.ThreePlayers = decoded.ThreePlayers
+	game.Moves = decoded.Moves
+	ref := func(id *ParticipantId) (*Participant, error) {
+		if id == nil {
+			return nil, nil
+		}
+		return game.participantById(*id)
+	}
+	if game.Player1, err = ref(decoded.Player1); err != nil {
+		return err
+	}
+	if game.Player2, err = ref(decoded.Player2); err != nil {
+		return err
+	}
+	if game.Player3, err = ref(decoded.Player3); err != nil {
+		return err
+	}
+	if game.CurrentPlayer, err = ref(decoded.CurrentPlayer); err != nil {
+		return err
+	}
+	if game.TakebackRequest, err = ref(decoded.Takeback); err != nil {
+		return err
+	}
+	game.UnlimitedUndo = decoded.UnlimitedUndo
+	game.Start = decoded.Start
+	if o := decoded.Outcome; o != nil {
+		game.Outcome = &Outcome{Kind: o.Kind, Reason: o.Reason}
+		if game.Outcome.Winner, err = ref(o.Winner); err != nil {
+			return err
+		}
+		if game.Outcome.Loser, err = ref(o.Loser); err != nil {
+			return err
+		}
+		if err := game.restoreOutcome(o.Ply); err != nil {
+			return err
+		}
+	}
+	for _, id := range decoded.DrawOffers {
+		p, err := game.participantById(id)
+		if err != nil {
+			return err
+		}
+		game.DrawOffers = append(game.DrawOffers, p)
+	}
+	if c := decoded.Clocks; c != nil {
+		if len(c.Remaining) != game.PlayerCount() {
+			return fmt.Errorf("%d clocks for %d players", len(c.Remaining), game.PlayerCount())
+		}
+		game.Clocks = NewClocks(c.Control, nil)
+		copy(game.Clocks.Remaining[:], c.Remaining)
+		if c.Started != nil {
+			game.Clocks.Started = *c.Started
+		}
+	}
+	if decoded.State == -1 {
+		game.State = game.inferredState()
+	}
+	if err := game.check(); err != nil {
+		return err
+	}
+
+	*g = *game
+	return nil
+}
+
+func (g *Game) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.byte(GameVersion)
+	e.uint(uint64(g.Id))
+	e.board(&g.Board)
+	e.bool(g.EarlyDraws)
+	e.uint(uint64(g.State))
+	e.uint(uint64(g.Variant))
+	e.bool(g.ThreePlayers)
+
+	participants := g.allParticipants()
+	index := func(p *Participant) uint64 {
+		for i, participant := range participants {
+			if participant == p {
+				return uint64(i + 1)
+			}
+		}
+		return 0
+	}
+	e.uint(uint64(len(participants)))
+	for _, p := range participants {
+		e.participant(p)
+	}
+	e.uint(index(g.Player1))
+	e.uint(index(g.Player2))
+	e.uint(index(g.Player3))
+	e.uint(index(g.CurrentPlayer))
+	e.moves(g.Moves)
+
+	e.bool(g.Outcome != nil)
+	if o := g.Outcome; o != nil {
+		e.uint(uint64(o.Kind))
+		e.uint(index(o.Winner))
+		e.uint(index(o.Loser))
+		e.string(o.Reason)
+		ply := 0
+		if o.Move != nil {
+			ply = o.Move.Ply
+		}
+		e.uint(uint64(ply))
+	}
+
+	e.bool(g.Clocks != nil)
+	if c := g.Clocks; c != nil {
+		e.int(int64(c.Control.Base))
+		e.int(int64(c.Control.Increment))
+		for _, left := range c.Remaining[:g.PlayerCount()] {
+			e.int(int64(left))
+		}
+		e.time(c.Started)
+	}
+
+	e.uint(uint64(len(g.DrawOffers)))
+	for _, p := range g.DrawOffers {
+		e.uint(index(p))
+	}
+	e.uint(index(g.TakebackRequest))
+	e.bool(g.UnlimitedUndo)
+
+	e.bool(g.Start != nil)
+	if s := g.Start; s != nil {
+		e.board(&s.Board)
+		e.uint(uint64(s.ToMove))
+	}
+	return e.buf, nil
+}
+
+func (g *Game) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	game, err := decodeGame(d, d.version(GameVersion))
+	if err != nil {
+		return err
+	}
+	*g = *game
+	return nil
+}
+
+// decodeGame reads a game in the given version of the binary format, what
+// older versions don't hold keeps its default
+func decodeGame(d *decoder, version byte) (*Game, error) {
+	id := GameId(d.uint())
+	board := d.board()
+	earlyDraws := d.bool()
+	state := Lobby
+	if version >= 2 {
+		state = GameState(d.uint())
+	}
+	variant := Classic
+	if version >= 3 {
+		variant = Variant(d.uint())
+	}
+	threePlayers := false
+	if version >= 5 {
+		threePlayers = d.bool()
+	}
+	participants := make([]*Participant, d.count())
+	for i := range participants {
+		participants[i] = d.participant()
+	}
+	ref := func() *Participant {
+		i := d.uint()
+		if i == 0 || i > uint64(len(participants)) {
+			return nil
+		}
+		return participants[i-1]
+	}
+	player1, player2 := ref(), ref()
+	var player3 *Participant
+	if version >= 5 {
+		player3 = ref()
+	}
+	current := ref()
+	moves := d.moves(version >= 4)
+	var outcome *Outcome
+	ply := 0
+	if d.bool() {
+		outcome = &Outcome{Kind: OutcomeKind(d.uint())}
+		outcome.Winner, outcome.Loser = ref(), ref()
+		outcome.Reason = d.string()
+		ply = int(d.uint())
+	}
+	var clocks *Clocks
+	if version >= 6 && d.bool() {
+		clocks = NewClocks(TimeControl{Base: time.Duration(d.int()), Increment: time.Duration(d.int())}, nil)
+		players := 2
+		if threePlayers {
+			players = 3
+		}
+		for i := 0; i < players; i++ {
+			clocks.Remaining[i] = time.Duration(d.int())
+		}
+		clocks.Started = d.time()
+	}
+	var drawOffers []*Participant
+	if version >= 7 {
+		drawOffers = make([]*Participant, d.count())
+		for i := range drawOffers {
+			drawOffers[i] = ref()
+		}
+	}
+	var takeback *Participant
+	unlimitedUndo := false
+	if version >= 8 {
+		takeback, unlimitedUndo = ref(), d.bool()
+	}
+	var start *Position
+	if version >= 9 && d.bool() {
+		start = &Position{Board: d.board()}
+		start.ToMove = int(d.uint())
+	}
+	if err := d.done(); err != nil {
+		return nil, err
+	}
+
+	game, err := newDecodedGame(id, board, participants)
+	if err != nil {
+		return nil, err
+	}
+	game.EarlyDraws = earlyDraws
+	game.State = state
+	game.Variant = variant
+	game.ThreePlayers = threePlayers
+	game.Player1, game.Player2, game.Player3, game.CurrentPlayer = player1, player2, player3, current
+	game.Moves = moves
+	game.Outcome = outcome
+	game.Clocks = clocks
+	game.DrawOffers = drawOffers
+	game.TakebackRequest, game.UnlimitedUndo = takeback, unlimitedUndo
+	game.Start = start
+	if outcome != nil {
+		if err := game.restoreOutcome(ply); err != nil {
+			return nil, err
+		}
+	}
+	if version < 2 {
+		game.State = game.inferredState()
+	}
+	if err := game.check(); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// allParticipants lists every participant including players that were seated
+// without joining (e.g. in code)
+func (g *Game) allParticipants() []*Participant {
+	var participants []*Participant
+	if g.Participants != nil {
+		for pair := g.Participants.Oldest(); pair != nil; pair = pair.Next() {
+			participants = append(participants, pair.Value)
+		}
+	}
+	for _, p := range []*Participant{g.Player1, g.Player2, g.Player3} {
+		if p == nil {
+			continue
+		}
+		if g.Participants == nil {
+			participants = append(participants, p)
+		} else if _, exists := g.Participants.Get(p.Id); !exists {
+			participants = append(participants, p)
+		}
+	}
+	return participants
+}
+
+func newDecodedGame(id GameId, board Board, participants []*Participant) (*Game, error) {
+	game := NewGameWithBoard(id, &board)
+	for _, p := range participants {
+		if p == nil {
+			return nil, errors.New("missing participant")
+		}
+		game.Participants.Set(p.Id, p)
+	}
+	return game, nil
+}
+
+func (g *Game) participantById(id ParticipantId) (*Participant, error) {
+	if p, exists := g.Participants.Get(id); exists {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown participant %q", id)
+}
+
+// check makes sure a decoded game is consistent: the board is the one the move
+// log leads to and only finished games have an outcome
+func (g *Game) check() error {
+	if g.State < Lobby || g.State > Abandoned {
+		return fmt.Errorf("unknown game state %d", g.State)
+	}
+	if err := g.Variant.checkBoard(&g.Board); err != nil {
+		return err
+	}
+	if g.ThreePlayers {
+		if err := CheckThreePlayers(g.Variant, &g.Board); err != nil {
+			return err
+		}
+	} else if g.Player3 != nil {
+		return errors.New("third player seated in a two player game")
+	}
+	if c := g.Clocks; c != nil {
+		if c.Control.Base <= 0 || c.Control.Increment < 0 {
+			return fmt.Errorf("invalid time control %s", c.Control)
+		}
+		for _, left := range c.Remaining {
+			if left < 0 {
+				return errors.New("negative time left on a clock")
+			}
+		}
+	}
+	for _, p := range g.DrawOffers {
+		if g.PlayerValue(p) == 0 || g.GameOver() {
+			return errors.New("draw offered by someone that cannot offer one")
+		}
+	}
+	if p := g.TakebackRequest; p != nil && (g.PlayerValue(p) == 0 || g.GameOver() || !g.Takebacks()) {
+		return errors.New("takeback requested by someone that cannot request one")
+	}
+	if g.Start != nil {
+		if err := g.checkStart(g.Start); err != nil {
+			return fmt.Errorf("invalid start position: %w", err)
+		}
+	}
+	if g.GameOver() != (g.Outcome != nil) {
+		return fmt.Errorf("%s game with outcome %v", stateName(g.State), g.Outcome != nil)
+	}
+	board := g.startBoard()
+	for i, move := range g.Moves {
+		if move.Ply != i+1 {
+			return fmt.Errorf("move %d has ply %d", i+1, move.Ply)
+		}
+		if board.GetCell(move.Cell) != 0b00 {
+			return fmt.Errorf("move %d plays an occupied cell", move.Ply)
+		}
+		if err := board.setCell(move.Cell, move.Piece); err != nil {
+			return fmt.Errorf("move %d: %w", move.Ply, err)
+		}
+	}
+	if board != g.Board {
+		return errors.New("board does not match the move log")
+	}
+	return nil
+}
+
+// inferredState works out the state of a game written before games had one
+// from what they held back then: the outcome, whether someone had the move
+// and who was seated
+func (g *Game) inferredState() GameState {
+	switch {
+	case g.Outcome != nil && g.Outcome.Kind == Abandonment:
+		return Abandoned
+	case g.Outcome != nil:
+		return Finished
+	case g.CurrentPlayer != nil:
+		return InProgress
+	case g.Player1 != nil:
+		return WaitingForOpponent
+	}
+	return Lobby
+}
+
+func (g *Game) restoreOutcome(ply int) error {
+	if ply < 0 || ply > len(g.Moves) {
+		return fmt.Errorf("outcome decided by unknown move %d", ply)
+	}
+	if ply > 0 {
+		g.Outcome.Move = &g.Moves[ply-1]
+	}
+	if g.Outcome.Kind == Win && g.Outcome.Move != nil {
+		g.Outcome.Line = g.CheckWinner()
+	}
+	return nil
+}
+
+// Binary helpers
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) byte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) uint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) int(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.uint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// board writes the size and then 4 cells per byte, 2 bits each
+func (e *encoder) board(b *Board) {
+	e.uint(uint64(b.width))
+	e.uint(uint64(b.height))
+	e.uint(uint64(b.winLength))
+	packed := make([]byte, (b.Size()+3)/4)
+	for i := 0; i < b.Size(); i++ {
+		packed[i/4] |= byte(b.GetCell(i)) << ((i % 4) * 2)
+	}
+	e.buf = append(e.buf, packed...)
+}
+
+func (e *encoder) participant(p *Participant) {
+	e.string(string(p.Id))
+	e.string(p.Name)
+	var flags byte
+	for i, flag := range []bool{p.Player, p.Connected, p.Bot} {
+		if flag {
+			flags |= 1 << i
+		}
+	}
+	e.byte(flags)
+}
+
+func (e *encoder) move(m *Move) {
+	e.uint(uint64(m.Player))
+	e.uint(uint64(m.Cell))
+	e.uint(uint64(m.Piece))
+	e.uint(uint64(m.Ply))
+	e.time(m.Time)
+}
+
+// time writes a flag and the unix nanoseconds of times that are set
+func (e *encoder) time(t time.Time) {
+	e.bool(!t.IsZero())
+	if !t.IsZero() {
+		e.int(t.UnixNano())
+	}
+}
+
+func (e *encoder) moves(moves MoveLog) {
+	e.uint(uint64(len(moves)))
+	for i := range moves {
+		e.move(&moves[i])
+	}
+}
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+var errShortBuffer = errors.New("unexpected end of data")
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+	d.buf = nil
+}
+
+func (d *decoder) done() error {
+	if d.err == nil && len(d.buf) > 0 {
+		return errors.New("unexpected data after the end")
+	}
+	return d.err
+}
+
+func (d *decoder) byte() byte {
+	if len(d.buf) == 0 {
+		d.fail(errShortBuffer)
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail(errShortBuffer)
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) int() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail(errShortBuffer)
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// version reads the version a record starts with, every version up to the
+// current one can be decoded
+func (d *decoder) version(current byte) byte {
+	v := d.byte()
+	if v == 0 || v > current {
+		d.fail(errEncodingVersion)
+	}
+	return v
+}
+
+// count reads a length that has to fit in the remaining data
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > uint64(len(d.buf)) {
+		d.fail(errShortBuffer)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bool() bool {
+	b := d.byte()
+	if b > 1 {
+		d.fail(fmt.Errorf("invalid bool %d", b))
+	}
+	return b == 1
+}
+
+func (d *decoder) string() string {
+	n := d.count()
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+
+func (d *decoder) board() Board {
+	width, height, winLength := int(d.uint()), int(d.uint()), int(d.uint())
+	if d.err != nil {
+		return Board{}
+	}
+	board, err := NewBoardWithSize(width, height, winLength)
+	if err != nil {
+		d.fail(err)
+		return Board{}
+	}
+	n := (board.Size() + 3) / 4
+	if len(d.buf) < n {
+		d.fail(errShortBuffer)
+		return Board{}
+	}
+	for i := 0; i < board.Size(); i++ {
+		if player := int(d.buf[i/4]>>((i%4)*2)) & 0b11; player != 0b00 {
+			if err := board.setCell(i, player); err != nil {
+				d.fail(err)
+				return Board{}
+			}
+		}
+	}
+	d.buf = d.buf[n:]
+	return *board
+}
+
+func (d *decoder) participant() *Participant {
+	p := &Participant{Id: ParticipantId(d.string()), Name: d.string()}
+	flags := d.byte()
+	p.Player, p.Connected, p.Bot = flags&1 != 0, flags&2 != 0, flags&4 != 0
+	return p
+}
+
+// move reads a move, moves written without their piece place the player's own
+func (d *decoder) move(piece bool) Move {
+	m := Move{Player: int(d.uint()), Cell: int(d.uint())}
+	m.Piece = m.Player
+	if piece {
+		m.Piece = int(d.uint())
+	}
+	m.Ply = int(d.uint())
+	m.Time = d.time()
+	return m
+}
+
+func (d *decoder) time() time.Time {
+	if !d.bool() {
+		return time.Time{}
+	}
+	return time.Unix(0, d.int())
+}
+
+func (d *decoder) moves(pieces bool) MoveLog {
+	moves := make(MoveLog, d.count())
+	for i := range moves {
+		moves[i] = d.move(pieces)
+	}
+	return moves
+}
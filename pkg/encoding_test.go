@@ -0,0 +1,196 @@
+package tictactoe
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBoardDecodesOlderVersions(t *testing.T) {
+	board := NewBoardWithValue(0b100001)
+	data, _ := board.MarshalBinary()
+	for version := byte(1); version <= BoardVersion; version++ {
+		data[0] = version
+		var decoded Board
+		if err := decoded.UnmarshalBinary(data); err != nil || decoded != *board {
+			t.Errorf("version %d: decoded %s, %v", version, decoded.Key(), err)
+		}
+	}
+
+	data[0] = BoardVersion + 1
+	var decoded Board
+	if err := decoded.UnmarshalBinary(data); !errors.Is(err, errEncodingVersion) {
+		t.Errorf("decoding a newer version: %v", err)
+	}
+}
+
+// Game 7 as every version of the encoder wrote it: alice took the top row,
+// bob played a2 and b2
+var encodedGames = []struct {
+	version byte
+	hex     string
+}{
+	{1, "0107030303950200000205616c69636505616c6963650303626f6203626f620301020105010001018080bc8cc6bfcce72f0203020180a892c6cdbfcce72f0101030180d0e8ffd4bfcce72f0204040180f8beb9dcbfcce72f0102050180a095f3e3bfcce72f010101000a3320696e206120726f7705"},
+	{2, "020703030395020000040205616c69636505616c6963650303626f6203626f620301020105010001018080bc8cc6bfcce72f0203020180a892c6cdbfcce72f0101030180d0e8ffd4bfcce72f0204040180f8beb9dcbfcce72f0102050180a095f3e3bfcce72f010101000a3320696e206120726f7705"},
+	{3, "03070303039502000004000205616c69636505616c6963650303626f6203626f620301020105010001018080bc8cc6bfcce72f0203020180a892c6cdbfcce72f0101030180d0e8ffd4bfcce72f0204040180f8beb9dcbfcce72f0102050180a095f3e3bfcce72f010101000a3320696e206120726f7705"},
+	{4, "04070303039502000004000205616c69636505616c6963650303626f6203626f62030102010501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f010101000a3320696e206120726f7705"},
+	{5, "0507030303950200000400000205616c69636505616c6963650303626f6203626f6203010200010501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f010101000a3320696e206120726f7705"},
+	{6, "0607030303950200000400000205616c69636505616c6963650303626f6203626f6203010200010501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f010101000a3320696e206120726f770500"},
+	{7, "0707030303950200000400000205616c69636505616c6963650303626f6203626f6203010200010501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f010101000a3320696e206120726f77050000"},
+	{8, "0807030303950200000400000205616c69636505616c6963650303626f6203626f6203010200010501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f010101000a3320696e206120726f770500000000"},
+	{9, "0907030303950200000400000205616c69636505616c6963650303626f6203626f6203010200010501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f010101000a3320696e206120726f77050000000000"},
+}
+
+// Move 2 of game 7 and all of its moves, as the versions that changed how
+// moves are written wrote them
+var encodedMoves = []struct {
+	version byte
+	move    string
+	log     string
+}{
+	{1, "010203020180a892c6cdbfcce72f", "0105010001018080bc8cc6bfcce72f0203020180a892c6cdbfcce72f0101030180d0e8ffd4bfcce72f0204040180f8beb9dcbfcce72f0102050180a095f3e3bfcce72f"},
+	{4, "04020302020180a892c6cdbfcce72f", "040501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f"},
+	{5, "05020302020180a892c6cdbfcce72f", "050501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f"},
+	{6, "06020302020180a892c6cdbfcce72f", "060501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f"},
+	{7, "07020302020180a892c6cdbfcce72f", "070501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f"},
+	{8, "08020302020180a892c6cdbfcce72f", "080501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f"},
+	{9, "09020302020180a892c6cdbfcce72f", "090501000101018080bc8cc6bfcce72f020302020180a892c6cdbfcce72f010101030180d0e8ffd4bfcce72f020402040180f8beb9dcbfcce72f010201050180a095f3e3bfcce72f"},
+}
+
+// encodedGame is the game the encoded games hold
+func encodedGame(t *testing.T) *Game {
+	game := NewGame(7)
+	game.Join("alice", "alice")
+	game.Join("bob", "bob")
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		if err := game.PlayMove(i%2+1, cell); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range game.Moves {
+		game.Moves[i].Time = time.Date(2024, 8, 1, 12, 0, i, 0, time.UTC)
+	}
+	return game
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGameDecodesOlderVersions(t *testing.T) {
+	want := encodedGame(t)
+	current, _ := want.MarshalBinary()
+	for _, encoded := range encodedGames {
+		if encoded.version == GameVersion && hex.EncodeToString(current) != encoded.hex {
+			t.Errorf("version %d is written as %x", GameVersion, current)
+		}
+		var decoded Game
+		if err := decoded.UnmarshalBinary(decodeHex(t, encoded.hex)); err != nil {
+			t.Errorf("version %d: %v", encoded.version, err)
+			continue
+		}
+		if decoded.Id != want.Id || decoded.State != want.State || decoded.Notation() != want.Notation() {
+			t.Errorf("version %d decoded as %s game %d\n%s", encoded.version, decoded.State, decoded.Id, decoded.Notation())
+		}
+		for i, move := range decoded.Moves {
+			if !sameMove(move, want.Moves[i]) {
+				t.Errorf("version %d: move %d decoded as %+v", encoded.version, i+1, move)
+			}
+		}
+	}
+}
+
+func TestGameStateOfVersion1(t *testing.T) {
+	for _, encoded := range []struct {
+		hex   string
+		state GameState
+	}{
+		{"0109030303000000000105616c69636505616c696365030100000000", WaitingForOpponent},
+		{"0108030303000100000205616c69636505616c6963650303626f6203626f620301020201010401018080bc8cc6bfcce72f00", InProgress},
+		{encodedGames[0].hex, Finished},
+	} {
+		var decoded Game
+		if err := decoded.UnmarshalBinary(decodeHex(t, encoded.hex)); err != nil || decoded.State != encoded.state {
+			t.Errorf("game %d decoded as %s, want %s: %v", decoded.Id, decoded.State, encoded.state, err)
+		}
+	}
+}
+
+func TestMovesDecodeOlderVersions(t *testing.T) {
+	want := encodedGame(t).Moves
+	for _, encoded := range encodedMoves {
+		var move Move
+		if err := move.UnmarshalBinary(decodeHex(t, encoded.move)); err != nil || !sameMove(move, want[1]) {
+			t.Errorf("version %d move decoded as %+v, %v", encoded.version, move, err)
+		}
+		var log MoveLog
+		if err := log.UnmarshalBinary(decodeHex(t, encoded.log)); err != nil || len(log) != len(want) {
+			t.Errorf("version %d move log decoded as %v, %v", encoded.version, log, err)
+			continue
+		}
+		for i, move := range log {
+			if !sameMove(move, want[i]) {
+				t.Errorf("move %d of a version %d log decoded as %+v", i+1, encoded.version, move)
+			}
+		}
+	}
+}
+
+func sameMove(a, b Move) bool {
+	return a.Player == b.Player && a.Cell == b.Cell && a.Piece == b.Piece && a.Ply == b.Ply && a.Time.Equal(b.Time)
+}
+
+func parseGame(t *testing.T, notation string) *Game {
+	game, err := ParseNotation(notation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+// gamesInEveryState returns a game in each state but paused, which games
+// written before they had a state never are
+func gamesInEveryState(t *testing.T) []*Game {
+	lobby := NewGame(1)
+	waiting := NewGame(2)
+	waiting.Join("alice", "alice")
+	abandoned := parseGame(t, "[X \"alice\"]\n[O \"bob\"]\n\n1. a1 b1")
+	if err := abandoned.Abandon("Nobody came back"); err != nil {
+		t.Fatal(err)
+	}
+	return []*Game{
+		lobby,
+		waiting,
+		parseGame(t, "[X \"alice\"]\n[O \"bob\"]\n\n1. a1 b1"),
+		encodedGame(t),
+		abandoned,
+	}
+}
+
+func TestGameJSONWithoutState(t *testing.T) {
+	for _, game := range gamesInEveryState(t) {
+		data, _ := json.Marshal(game)
+		var fields map[string]any
+		json.Unmarshal(data, &fields)
+		delete(fields, "state")
+		data, _ = json.Marshal(fields)
+
+		var decoded Game
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded.State != game.State {
+			t.Errorf("%s game without a state decoded as %s, %v", game.State, decoded.State, err)
+		}
+	}
+}
+
+func TestMoveJSONWithoutPiece(t *testing.T) {
+	var move Move
+	if err := json.Unmarshal([]byte(`{"player":2,"cell":4,"ply":2}`), &move); err != nil || move.Piece != 0b10 {
+		t.Errorf("move without a piece in JSON decoded as %+v, %v", move, err)
+	}
+}
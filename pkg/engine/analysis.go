@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"fmt"
+
+	tictactoe "jay/tictactoe/pkg"
+)
+
+// Depth used when analysing boards too large to solve
+const analysisDepth = 2
+
+// CellAnalysis is the result of the player to move playing a cell
+type CellAnalysis struct {
+	Cell  int   `json:"cell"`
+	Value Value `json:"value"`
+	// Plies until the result is reached with best play, including this move
+	Distance int  `json:"distance"`
+	Exact    bool `json:"exact"`
+}
+
+func (a CellAnalysis) Label() string {
+	switch a.Value {
+	case Win:
+		return fmt.Sprintf("W%d", a.Distance)
+	case Loss:
+		return fmt.Sprintf("L%d", a.Distance)
+	}
+	return "D"
+}
+
+// Analyze evaluates every empty cell for the player to move
+func (s *Solver) Analyze(board tictactoe.Board, player int) []CellAnalysis {
+	if board.WinningLine() != nil || board.Full() {
+		return nil
+	}
+
+	var cells []CellAnalysis
+	for i := 0; i < board.Size(); i++ {
+		next, err := board.WithMove(i, player)
+		if err != nil {
+			continue
+		}
+		analysis := CellAnalysis{Cell: i, Value: Draw, Distance: 1, Exact: true}
+		switch {
+		case next.WinningLineThrough(i) != nil:
+			analysis.Value = Win
+		case next.Full():
+		default:
+			reply := s.Solve(next, tictactoe.Opponent(player))
+			analysis.Value = -reply.Value
+			analysis.Distance = reply.Distance + 1
+			analysis.Exact = reply.Exact
+		}
+		cells = append(cells, analysis)
+	}
+	return cells
+}
+
+// Analyze evaluates every empty cell of a board, boards too large to solve
+// get a shallow search instead
+func Analyze(board tictactoe.Board, player int) []CellAnalysis {
+	solver := NewSolver()
+	if board.Size() > MaxSolvedCells {
+		solver.MaxDepth = analysisDepth
+		solver.Symmetric = false
+	}
+	return solver.Analyze(board, player)
+}
+
+func (v Value) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// Best returns the strongest of the analysed cells: the fastest win, else a
+// draw, else the slowest loss
+func Best(cells []CellAnalysis) (CellAnalysis, bool) {
+	if len(cells) == 0 {
+		return CellAnalysis{}, false
+	}
+	best := cells[0]
+	for _, cell := range cells[1:] {
+		if cell.rank() > best.rank() {
+			best = cell
+		}
+	}
+	return best, true
+}
+
+func (a CellAnalysis) rank() int {
+	switch a.Value {
+	case Win:
+		return 2*tictactoe.MaxBoardCells - a.Distance
+	case Loss:
+		return -2*tictactoe.MaxBoardCells + a.Distance
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+
+	tictactoe "jay/tictactoe/pkg"
+)
+
+func TestAnalyzeMatchesBruteForce(t *testing.T) {
+	solver := NewSolver()
+	cache := map[tictactoe.Board]outcome{}
+	seen := map[tictactoe.Board]bool{}
+	reachable(newBoard(t, 3, 3, 3), 0b01, seen, func(b tictactoe.Board, player int) {
+		cells := solver.Analyze(b, player)
+		if len(cells) != len(emptyCells(&b)) {
+			t.Fatalf("%s: %d cells analysed, %d are empty", b.Key(), len(cells), len(emptyCells(&b)))
+		}
+		for _, cell := range cells {
+			next, err := b.WithMove(cell.Cell, player)
+			if err != nil {
+				t.Fatalf("%s: analysed cell %d: %v", b.Key(), cell.Cell, err)
+			}
+			want := afterMove(next, cell.Cell, player, cache)
+			if cell.Value != want.value || !cell.Exact || (want.value != Draw && cell.Distance != want.distance) {
+				t.Fatalf("%s: cell %d is %s, brute force %s in %d", b.Key(), cell.Cell, cell.Label(), want.value, want.distance)
+			}
+		}
+		best, _ := Best(cells)
+		if result := solver.Solve(b, player); best.Value != result.Value || (result.Value != Draw && best.Distance != result.Distance) {
+			t.Fatalf("%s: best cell is %s, Solve %s in %d", b.Key(), best.Label(), result.Value, result.Distance)
+		}
+	})
+}
+
+func TestAnalyzeGameOver(t *testing.T) {
+	for _, key := range []string{"3x3/3:XXX/OO./...", "3x3/3:XOX/XOO/OXX"} {
+		if cells := Analyze(parseBoard(t, key), 0b10); cells != nil {
+			t.Errorf("%s: analysed %v in a finished game", key, cells)
+		}
+	}
+}
+
+func TestAnalyzeLargeBoard(t *testing.T) {
+	b := newBoard(t, 5, 5, 4)
+	if cells := Analyze(b, 0b01); len(cells) != b.Size() {
+		t.Errorf("%d cells analysed on an empty %s", len(cells), b.Key())
+	}
+}
+
+func TestBest(t *testing.T) {
+	tests := []struct {
+		cells []CellAnalysis
+		want  string
+	}{
+		{[]CellAnalysis{{Cell: 0, Value: Loss, Distance: 2}, {Cell: 1, Value: Loss, Distance: 4}}, "L4"},
+		{[]CellAnalysis{{Cell: 0, Value: Loss, Distance: 4}, {Cell: 1, Value: Draw, Distance: 1}}, "D"},
+		{[]CellAnalysis{{Cell: 0, Value: Win, Distance: 5}, {Cell: 1, Value: Draw}, {Cell: 2, Value: Win, Distance: 3}}, "W3"},
+	}
+	for _, test := range tests {
+		if best, ok := Best(test.cells); !ok || best.Label() != test.want {
+			t.Errorf("Best(%v) = %s, want %s", test.cells, best.Label(), test.want)
+		}
+	}
+	if _, ok := Best(nil); ok {
+		t.Error("found a best cell among none")
+	}
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	tictactoe "jay/tictactoe/pkg"
+)
+
+// Level is the strength of a computer opponent
+type Level int
+
+const (
+	// Random plays any empty cell
+	Random Level = iota
+	// Heuristic looks one move ahead: win, block, center, corner
+	Heuristic
+	// Imperfect plays perfectly except for the occasional blunder
+	Imperfect
+	// Perfect always plays the best move
+	Perfect
+)
+
+var Levels = []Level{Random, Heuristic, Imperfect, Perfect}
+
+func (l Level) String() string {
+	switch l {
+	case Random:
+		return "random"
+	case Heuristic:
+		return "heuristic"
+	case Imperfect:
+		return "imperfect"
+	case Perfect:
+		return "perfect"
+	}
+	return "unknown"
+}
+
+// CheckBoard reports whether the level lives up to its name on the board,
+// only boards small enough to be solved get a perfect opponent
+func (l Level) CheckBoard(board *tictactoe.Board) error {
+	if l == Perfect && board.Size() > MaxSolvedCells {
+		return fmt.Errorf("A perfect opponent only plays boards of up to %d cells", MaxSolvedCells)
+	}
+	return nil
+}
+
+func ParseLevel(s string) (Level, error) {
+	for _, level := range Levels {
+		if level.String() == s {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown bot level %q", s)
+}
+
+// Boards larger than this are searched to a limited depth, solving them is
+// out of reach
+const MaxSolvedCells = 16
+
+const perfectDepth = 4
+
+// Bot picks moves for a computer opponent. It is not safe for concurrent use
+type Bot struct {
+	Level Level
+	// Chance of an Imperfect bot playing a random move instead of the best one
+	ErrorRate float64
+	Rand      *rand.Rand
+	solver    *Solver
+}
+
+func NewBot(level Level) *Bot {
+	return &Bot{
+		Level:     level,
+		ErrorRate: 0.25,
+		Rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		solver:    NewSolver(),
+	}
+}
+
+// Move returns the cell the bot plays for the given player
+func (b *Bot) Move(board tictactoe.Board, player int) (int, error) {
+	if board.WinningLine() != nil || board.Full() {
+		return -1, errors.New("The game has already ended")
+	}
+
+	switch b.Level {
+	case Random:
+		return b.randomMove(&board), nil
+	case Heuristic:
+		return b.heuristicMove(&board, player), nil
+	case Imperfect:
+		if b.Rand.Float64() < b.ErrorRate {
+			return b.randomMove(&board), nil
+		}
+		return b.bestMove(board, player), nil
+	case Perfect:
+		return b.bestMove(board, player), nil
+	}
+	return -1, fmt.Errorf("unknown bot level %d", b.Level)
+}
+
+func (b *Bot) bestMove(board tictactoe.Board, player int) int {
+	solver := b.solver
+	if board.Size() > MaxSolvedCells {
+		// A fresh table per move keeps depth limited searches from piling up
+		solver = NewSolver()
+		solver.MaxDepth = perfectDepth
+		solver.Symmetric = false
+	}
+	return solver.Solve(board, player).Move
+}
+
+func (b *Bot) randomMove(board *tictactoe.Board) int {
+	empty := emptyCells(board)
+	return empty[b.Rand.Intn(len(empty))]
+}
+
+// heuristicMove wins if it can, blocks the opponent's win, and otherwise
+// prefers the center and then the corners
+func (b *Bot) heuristicMove(board *tictactoe.Board, player int) int {
+	empty := emptyCells(board)
+	for _, p := range []int{player, tictactoe.Opponent(player)} {
+		for _, cell := range empty {
+			next, _ := board.WithMove(cell, p)
+			if next.WinningLineThrough(cell) != nil {
+				return cell
+			}
+		}
+	}
+
+	if center := centerOrder(board.Width(), board.Height())[0]; board.GetCell(center) == 0b00 {
+		return center
+	}
+
+	w, h := board.Width(), board.Height()
+	var corners []int
+	for _, cell := range []int{0, w - 1, (h - 1) * w, h*w - 1} {
+		if board.GetCell(cell) == 0b00 {
+			corners = append(corners, cell)
+		}
+	}
+	if len(corners) > 0 {
+		return corners[b.Rand.Intn(len(corners))]
+	}
+
+	return empty[b.Rand.Intn(len(empty))]
+}
+
+func emptyCells(board *tictactoe.Board) []int {
+	var empty []int
+	for i := 0; i < board.Size(); i++ {
+		if board.GetCell(i) == 0b00 {
+			empty = append(empty, i)
+		}
+	}
+	return empty
+}
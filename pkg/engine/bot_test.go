@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	tictactoe "jay/tictactoe/pkg"
+)
+
+func newTestBot(level Level, errorRate float64) *Bot {
+	bot := NewBot(level)
+	bot.ErrorRate = errorRate
+	bot.Rand = rand.New(rand.NewSource(1))
+	return bot
+}
+
+// playOut plays the bot against every possible reply and fails if any of the
+// games ends worse for the bot than the value it can hold
+func playOut(t *testing.T, bot *Bot, b tictactoe.Board, player int, botPlayer int, least Value) {
+	t.Helper()
+	if line := b.WinningLine(); line != nil {
+		if b.GetCell(line.Cells[0]) != botPlayer {
+			t.Fatalf("the %s bot lost %s", bot.Level, b.Key())
+		}
+		return
+	}
+	if b.Full() {
+		if least == Win {
+			t.Fatalf("the %s bot only drew %s", bot.Level, b.Key())
+		}
+		return
+	}
+	if player == botPlayer {
+		cell, err := bot.Move(b, player)
+		if err != nil {
+			t.Fatalf("%s: %v", b.Key(), err)
+		}
+		next, err := b.WithMove(cell, player)
+		if err != nil {
+			t.Fatalf("%s: the bot played %d: %v", b.Key(), cell, err)
+		}
+		playOut(t, bot, next, tictactoe.Opponent(player), botPlayer, least)
+		return
+	}
+	for cell := 0; cell < b.Size(); cell++ {
+		if next, err := b.WithMove(cell, player); err == nil {
+			playOut(t, bot, next, tictactoe.Opponent(player), botPlayer, least)
+		}
+	}
+}
+
+func TestBotKeepsTheValueOfTheStart(t *testing.T) {
+	// Imperfect without blunders plays like Perfect
+	bots := []*Bot{newTestBot(Perfect, 0), newTestBot(Imperfect, 0)}
+	tests := []struct {
+		board  tictactoe.Board
+		player int
+		least  Value
+	}{
+		// Nobody wins 3x3 with perfect play
+		{newBoard(t, 3, 3, 3), 0b01, Draw},
+		{newBoard(t, 3, 3, 3), 0b10, Draw},
+		// X wins 4x3 by force
+		{newBoard(t, 4, 3, 3), 0b01, Win},
+	}
+	for _, test := range tests {
+		if value := Solve(test.board, 0b01).Value; (value == Win) != (test.least == Win) {
+			t.Fatalf("%s is a %s for X", test.board.Key(), value)
+		}
+		for _, bot := range bots {
+			playOut(t, bot, test.board, 0b01, test.player, test.least)
+		}
+	}
+}
+
+func TestBotPlaysLegalMoves(t *testing.T) {
+	boards := []tictactoe.Board{
+		newBoard(t, 3, 3, 3),
+		parseBoard(t, "3x3/3:XOX/OXX/O.O"),
+		newBoard(t, 4, 4, 3),
+		newBoard(t, 5, 5, 4),
+	}
+	for _, level := range Levels {
+		for _, errorRate := range []float64{0, 0.25, 1} {
+			bot := newTestBot(level, errorRate)
+			for _, board := range boards {
+				b, player := board, 0b01
+				for b.WinningLine() == nil && !b.Full() {
+					cell, err := bot.Move(b, player)
+					if err != nil {
+						t.Fatalf("%s bot on %s: %v", level, b.Key(), err)
+					}
+					next, err := b.WithMove(cell, player)
+					if err != nil {
+						t.Fatalf("%s bot played %d on %s: %v", level, cell, b.Key(), err)
+					}
+					b, player = next, tictactoe.Opponent(player)
+				}
+				if _, err := bot.Move(b, player); err == nil {
+					t.Errorf("%s bot moved after %s was over", level, b.Key())
+				}
+			}
+		}
+	}
+}
+
+func TestHeuristicMove(t *testing.T) {
+	tests := []struct {
+		board  string
+		player int
+		want   []int
+	}{
+		{"3x3/3:XX./OO./...", 0b01, []int{2}},
+		{"3x3/3:XX./OO./...", 0b10, []int{5}},
+		{"3x3/3:XX./O../...", 0b10, []int{2}},
+		{"3x3/3:X../.../...", 0b10, []int{4}},
+		{"3x3/3:.../.X./...", 0b10, []int{0, 2, 6, 8}},
+	}
+	bot := newTestBot(Heuristic, 0)
+	for _, test := range tests {
+		cell, err := bot.Move(parseBoard(t, test.board), test.player)
+		if err != nil || !slices.Contains(test.want, cell) {
+			t.Errorf("%s: played %d (%v), want one of %v", test.board, cell, err, test.want)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range Levels {
+		if parsed, err := ParseLevel(level.String()); err != nil || parsed != level {
+			t.Errorf("ParseLevel(%q) = %s, %v", level, parsed, err)
+		}
+	}
+	if _, err := ParseLevel("grandmaster"); err == nil {
+		t.Error("parsed an unknown level")
+	}
+}
+
+func TestLevelCheckBoard(t *testing.T) {
+	tests := []struct {
+		level Level
+		board tictactoe.Board
+		ok    bool
+	}{
+		{Perfect, newBoard(t, 3, 3, 3), true},
+		{Perfect, newBoard(t, 4, 4, 3), true},
+		{Perfect, newBoard(t, 5, 5, 4), false},
+		{Imperfect, newBoard(t, 5, 5, 4), true},
+		{Random, newBoard(t, 5, 5, 4), true},
+	}
+	for _, test := range tests {
+		if err := test.level.CheckBoard(&test.board); (err == nil) != test.ok {
+			t.Errorf("%s on %s: %v", test.level, test.board.Key(), err)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Package engine searches tic-tac-toe positions for the best move
+package engine
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	tictactoe "jay/tictactoe/pkg"
+)
+
+// Value is the game-theoretic result of a position for the player to move
+type Value int
+
+const (
+	Loss Value = -1
+	Draw Value = 0
+	Win  Value = 1
+)
+
+func (v Value) String() string {
+	switch v {
+	case Loss:
+		return "Loss"
+	case Draw:
+		return "Draw"
+	case Win:
+		return "Win"
+	}
+	return "Unknown"
+}
+
+type Result struct {
+	// Best cell for the player to move, -1 if the game is already over
+	Move  int
+	Value Value
+	// Number of plies until the result is reached with best play
+	Distance int
+	// Principal variation, the expected sequence of cells starting with Move
+	PV []int
+	// False if the search was cut off by MaxDepth and Value is a guess
+	Exact bool
+}
+
+// Scores are win/loss distances from the root, larger than any board so
+// that faster wins and slower losses are preferred
+const winScore = 1000
+
+type bound uint8
+
+const (
+	exact bound = iota
+	lowerBound
+	upperBound
+)
+
+type entry struct {
+	score     int
+	remaining int
+	flag      bound
+	best      int
+	// Score relied on a depth cut off somewhere below this position
+	approx bool
+}
+
+// Solver runs a negamax search with alpha-beta pruning. Its transposition
+// table is kept between searches so it is not safe for concurrent use
+type Solver struct {
+	// Maximum number of plies to search, 0 searches to the end of the game
+	MaxDepth int
+	// Share table entries between rotated and reflected positions
+	Symmetric bool
+	table     map[tictactoe.Board]entry
+	cutoff    bool
+}
+
+func NewSolver() *Solver {
+	return &Solver{
+		Symmetric: true,
+		table:     make(map[tictactoe.Board]entry),
+	}
+}
+
+// Solve searches the position for the given player to move
+func Solve(board tictactoe.Board, player int) Result {
+	return NewSolver().Solve(board, player)
+}
+
+// BestMove searches the current position of a game that is still in progress
+func (s *Solver) BestMove(game *tictactoe.Game) (Result, error) {
+	if game.GameOver() {
+		return Result{}, errors.New("The game has already ended")
+	}
+	player := game.CurrentPlayerValue()
+	if player == 0 {
+		player = game.Rules().SideToMove(game)
+	}
+	return s.Solve(game.Board, player), nil
+}
+
+func (s *Solver) Solve(board tictactoe.Board, player int) Result {
+	if board.WinningLine() != nil {
+		return Result{Move: -1, Value: Loss, Exact: true}
+	}
+	if board.Full() {
+		return Result{Move: -1, Value: Draw, Exact: true}
+	}
+
+	s.cutoff = false
+	score := s.search(board, player, 0, s.remaining(), -winScore, winScore)
+	pv := s.principalVariation(board, player)
+	result := Result{Move: -1, PV: pv, Exact: !s.cutoff}
+	if len(pv) > 0 {
+		result.Move = pv[0]
+	}
+
+	switch {
+	case score > winScore/2:
+		result.Value = Win
+		result.Distance = winScore - score
+	case score < -winScore/2:
+		result.Value = Loss
+		result.Distance = winScore + score
+	default:
+		result.Value = Draw
+		result.Distance = len(pv)
+	}
+	return result
+}
+
+func (s *Solver) remaining() int {
+	if s.MaxDepth <= 0 {
+		return tictactoe.MaxBoardCells
+	}
+	return s.MaxDepth
+}
+
+func (s *Solver) search(b tictactoe.Board, player int, ply int, remaining int, alpha int, beta int) int {
+	tableBest := -1
+	if e, exists := s.lookup(&b); exists {
+		tableBest = e.best
+		if e.remaining >= remaining {
+			score := fromTable(e.score, ply)
+			switch e.flag {
+			case exact:
+				s.cutoff = s.cutoff || e.approx
+				return score
+			case lowerBound:
+				alpha = max(alpha, score)
+			case upperBound:
+				beta = min(beta, score)
+			}
+			if alpha >= beta {
+				s.cutoff = s.cutoff || e.approx
+				return score
+			}
+		}
+	}
+
+	if b.Drawn(player) {
+		// Nobody can win anymore, whatever is played keeps the draw
+		s.store(&b, entry{remaining: tictactoe.MaxBoardCells, flag: exact, best: firstEmpty(&b)})
+		return 0
+	}
+	if remaining == 0 {
+		s.cutoff = true
+		return 0
+	}
+
+	cutoff := s.cutoff
+	s.cutoff = false
+	alphaStart := alpha
+	best, bestMove := -winScore-1, -1
+	for _, move := range s.moves(&b, tableBest) {
+		next, _ := b.WithMove(move, player)
+		var score int
+		switch {
+		case next.WinningLineThrough(move) != nil:
+			score = winScore - (ply + 1)
+		case next.Full():
+			score = 0
+		default:
+			score = -s.search(next, tictactoe.Opponent(player), ply+1, remaining-1, -beta, -alpha)
+		}
+		if score > best {
+			best, bestMove = score, move
+		}
+		alpha = max(alpha, score)
+		if alpha >= beta {
+			break
+		}
+	}
+
+	flag := exact
+	if best <= alphaStart {
+		flag = upperBound
+	} else if best >= beta {
+		flag = lowerBound
+	}
+	s.store(&b, entry{
+		score:     toTable(best, ply),
+		remaining: remaining,
+		flag:      flag,
+		best:      bestMove,
+		approx:    s.cutoff,
+	})
+	s.cutoff = s.cutoff || cutoff
+	return best
+}
+
+// lookup finds the table entry of a position, its best move is translated
+// back from the canonical position the entry was stored under
+func (s *Solver) lookup(b *tictactoe.Board) (entry, bool) {
+	if !s.Symmetric {
+		e, exists := s.table[*b]
+		return e, exists
+	}
+	key, symmetry := b.Canonical()
+	e, exists := s.table[key]
+	if exists && e.best >= 0 {
+		e.best = b.MapCell(symmetry.Inverse(), e.best)
+	}
+	return e, exists
+}
+
+func (s *Solver) store(b *tictactoe.Board, e entry) {
+	if !s.Symmetric {
+		s.table[*b] = e
+		return
+	}
+	key, symmetry := b.Canonical()
+	if e.best >= 0 {
+		e.best = b.MapCell(symmetry, e.best)
+	}
+	s.table[key] = e
+}
+
+// moves orders the empty cells with the table's best move first and then from
+// the center outwards. Depth limited searches only look at cells next to
+// pieces that have already been played
+func (s *Solver) moves(b *tictactoe.Board, first int) []int {
+	moves := make([]int, 0, b.Size())
+	if first >= 0 {
+		moves = append(moves, first)
+	}
+	nearbyOnly := s.MaxDepth > 0 && b.MoveCount() > 0
+	for _, cell := range centerOrder(b.Width(), b.Height()) {
+		if cell == first || b.GetCell(cell) != 0b00 {
+			continue
+		}
+		if nearbyOnly && !hasNeighbour(b, cell) {
+			continue
+		}
+		moves = append(moves, cell)
+	}
+	return moves
+}
+
+// firstEmpty returns the empty cell closest to the center
+func firstEmpty(b *tictactoe.Board) int {
+	for _, cell := range centerOrder(b.Width(), b.Height()) {
+		if b.GetCell(cell) == 0b00 {
+			return cell
+		}
+	}
+	return -1
+}
+
+func hasNeighbour(b *tictactoe.Board, cell int) bool {
+	row, col := cell/b.Width(), cell%b.Width()
+	for r := max(row-1, 0); r <= min(row+1, b.Height()-1); r++ {
+		for c := max(col-1, 0); c <= min(col+1, b.Width()-1); c++ {
+			if b.GetCell(r*b.Width()+c) != 0b00 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	centerOrdersMu sync.Mutex
+	centerOrders   = map[[2]int][]int{}
+)
+
+// centerOrder returns every cell of a board sorted by distance from the center
+func centerOrder(width int, height int) []int {
+	centerOrdersMu.Lock()
+	defer centerOrdersMu.Unlock()
+	if order, exists := centerOrders[[2]int{width, height}]; exists {
+		return order
+	}
+
+	order := make([]int, width*height)
+	for i := range order {
+		order[i] = i
+	}
+	distance := func(cell int) int {
+		dr, dc := 2*(cell/width)-(height-1), 2*(cell%width)-(width-1)
+		return dr*dr + dc*dc
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distance(order[i]) < distance(order[j])
+	})
+	centerOrders[[2]int{width, height}] = order
+	return order
+}
+
+// principalVariation follows the best moves stored in the transposition table
+func (s *Solver) principalVariation(b tictactoe.Board, player int) []int {
+	var pv []int
+	for len(pv) < b.Size() {
+		e, exists := s.lookup(&b)
+		if !exists || e.best < 0 {
+			break
+		}
+		next, err := b.WithMove(e.best, player)
+		if err != nil {
+			break
+		}
+		pv = append(pv, e.best)
+		if next.WinningLineThrough(e.best) != nil || next.Full() {
+			break
+		}
+		b, player = next, tictactoe.Opponent(player)
+	}
+	return pv
+}
+
+// Win and loss scores are stored relative to the position they were found in
+// so that they stay valid when the position is reached at a different ply
+func toTable(score int, ply int) int {
+	switch {
+	case score > winScore/2:
+		return score + ply
+	case score < -winScore/2:
+		return score - ply
+	}
+	return score
+}
+
+func fromTable(score int, ply int) int {
+	switch {
+	case score > winScore/2:
+		return score - ply
+	case score < -winScore/2:
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"testing"
+
+	tictactoe "jay/tictactoe/pkg"
+)
+
+// outcome is the value of a position for the player to move and the plies
+// until it is reached
+type outcome struct {
+	value    Value
+	distance int
+}
+
+// better reports whether o is a better result than other for the player to
+// move: the fastest win, else a draw, else the slowest loss
+func (o outcome) better(other outcome) bool {
+	if o.value != other.value {
+		return o.value > other.value
+	}
+	switch o.value {
+	case Win:
+		return o.distance < other.distance
+	case Loss:
+		return o.distance > other.distance
+	}
+	return false
+}
+
+// bruteForce searches every move of a position that is not over without any
+// pruning or tables other than a plain cache
+func bruteForce(b tictactoe.Board, player int, cache map[tictactoe.Board]outcome) outcome {
+	if o, exists := cache[b]; exists {
+		return o
+	}
+	best := outcome{value: Loss - 1}
+	for cell := 0; cell < b.Size(); cell++ {
+		next, err := b.WithMove(cell, player)
+		if err != nil {
+			continue
+		}
+		if o := afterMove(next, cell, player, cache); o.better(best) {
+			best = o
+		}
+	}
+	cache[b] = best
+	return best
+}
+
+// reachable calls visit with every position that is not over and can come up
+// in a game on the board, and the player to move
+func reachable(b tictactoe.Board, player int, seen map[tictactoe.Board]bool, visit func(b tictactoe.Board, player int)) {
+	if seen[b] || b.WinningLine() != nil || b.Full() {
+		return
+	}
+	seen[b] = true
+	visit(b, player)
+	for cell := 0; cell < b.Size(); cell++ {
+		if next, err := b.WithMove(cell, player); err == nil {
+			reachable(next, tictactoe.Opponent(player), seen, visit)
+		}
+	}
+}
+
+func parseBoard(t *testing.T, key string) tictactoe.Board {
+	var b tictactoe.Board
+	if err := b.UnmarshalText([]byte(key)); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newBoard(t *testing.T, width, height, winLength int) tictactoe.Board {
+	b, err := tictactoe.NewBoardWithSize(width, height, winLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *b
+}
+
+// afterMove is the outcome for the player that just played cell, next is the
+// board with the move
+func afterMove(next tictactoe.Board, cell int, player int, cache map[tictactoe.Board]outcome) outcome {
+	switch {
+	case next.WinningLineThrough(cell) != nil:
+		return outcome{value: Win, distance: 1}
+	case next.Full():
+		return outcome{value: Draw, distance: 1}
+	}
+	reply := bruteForce(next, tictactoe.Opponent(player), cache)
+	return outcome{value: -reply.value, distance: reply.distance + 1}
+}
+
+// checkSolve compares Solve with the brute force search on a position and
+// checks that it picks a legal move that keeps the value
+func checkSolve(t *testing.T, solver *Solver, b tictactoe.Board, player int, cache map[tictactoe.Board]outcome) {
+	t.Helper()
+	want := bruteForce(b, player, cache)
+	result := solver.Solve(b, player)
+	if result.Value != want.value || !result.Exact {
+		t.Fatalf("%s: Solve = %s (exact %v), brute force %s", b.Key(), result.Value, result.Exact, want.value)
+	}
+	if want.value != Draw && result.Distance != want.distance {
+		t.Fatalf("%s: %s in %d, brute force in %d", b.Key(), result.Value, result.Distance, want.distance)
+	}
+	next, err := b.WithMove(result.Move, player)
+	if err != nil {
+		t.Fatalf("%s: Solve picked %d: %v", b.Key(), result.Move, err)
+	}
+	if len(result.PV) == 0 || result.PV[0] != result.Move {
+		t.Fatalf("%s: move %d doesn't start the PV %v", b.Key(), result.Move, result.PV)
+	}
+	if played := afterMove(next, result.Move, player, cache); want.better(played) {
+		t.Fatalf("%s: move %d only gets %s in %d", b.Key(), result.Move, played.value, played.distance)
+	}
+}
+
+func TestSolveMatchesBruteForce(t *testing.T) {
+	boards := []tictactoe.Board{
+		newBoard(t, 3, 3, 3),
+		newBoard(t, 4, 3, 3),
+		newBoard(t, 3, 3, 2),
+	}
+	for _, board := range boards {
+		// One solver for every position, like a bot keeps its table
+		solver := NewSolver()
+		cache := map[tictactoe.Board]outcome{}
+		seen := map[tictactoe.Board]bool{}
+		positions := 0
+		reachable(board, 0b01, seen, func(b tictactoe.Board, player int) {
+			positions++
+			checkSolve(t, solver, b, player, cache)
+		})
+		if positions == 0 {
+			t.Errorf("no positions on %s", board.Key())
+		}
+	}
+}
+
+func TestSolvePlaysOnInDrawnPositions(t *testing.T) {
+	// Nobody can complete a line anymore, a move still has to be played
+	b := parseBoard(t, "3x3/3:XOX/OXX/O.O")
+	result := Solve(b, 0b01)
+	if result.Value != Draw || result.Move != 7 {
+		t.Errorf("Solve = %s with move %d, want a draw at 7", result.Value, result.Move)
+	}
+}
+
+func TestSolveGameOver(t *testing.T) {
+	for _, key := range []string{"3x3/3:XXX/OO./...", "3x3/3:XOX/XOO/OXX"} {
+		if result := Solve(parseBoard(t, key), 0b10); result.Move != -1 {
+			t.Errorf("%s: Solve picked %d in a finished game", key, result.Move)
+		}
+	}
+}
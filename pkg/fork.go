@@ -0,0 +1,26 @@
+package tictactoe
+
+import "errors"
+
+// Fork starts a new game from the position after the given number of moves.
+// The moves up to there are copied, so the new game can be stepped through
+// from the start
+func (g *Game) Fork(ply int) (*Game, error) {
+	board, err := g.BoardAt(ply)
+	if err != nil {
+		return nil, err
+	}
+	fork := NewGameWithBoard(0, &board)
+	fork.Variant = g.Variant
+	fork.ThreePlayers = g.ThreePlayers
+	fork.EarlyDraws = g.EarlyDraws
+	fork.Start = g.Start
+	fork.Moves = make(MoveLog, ply)
+	copy(fork.Moves, g.Moves[:ply])
+	if fork.Rules().Outcome(fork) != nil {
+		return nil, errors.New("The game was already decided at that move")
+	}
+	fork.ForkOf = g
+	fork.ForkPly = ply
+	return fork, nil
+}
@@ -2,6 +2,8 @@ package tictactoe
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
@@ -13,10 +15,11 @@ type GameId int
 type ParticipantId string
 
 type Participant struct {
-	Id        ParticipantId
-	Name      string
-	Player    bool
-	Connected bool
+	Id        ParticipantId `json:"id"`
+	Name      string        `json:"name"`
+	Player    bool          `json:"player"`
+	Connected bool          `json:"connected"`
+	Bot       bool          `json:"bot"`
 }
 
 type Game struct {
@@ -24,67 +27,136 @@ type Game struct {
 	Board         Board
 	Player1       *Participant
 	Player2       *Participant
-	Winner        *Participant
+	Player3       *Participant // Only seated in three player games
+	Outcome       *Outcome     // nil while the game is still being played
 	Participants  *orderedmap.OrderedMap[ParticipantId, *Participant]
-	History       []Board
+	Moves         MoveLog // Every move played so far, past boards are derived from it
 	CurrentPlayer *Participant
+	EarlyDraws    bool // End in a draw as soon as nobody can complete a line anymore
+	State         GameState
+	Variant       Variant
+	ThreePlayers  bool    // X, O and Y take turns
+	Clocks        *Clocks // nil for untimed games
+	// Players that offered or accepted a draw, empty while no offer is pending
+	DrawOffers []*Participant
+	// Players that want to play the finished game again
+	RematchOffers []*Participant
+	RematchOf     *Game     // Game this one is a rematch of, nil otherwise
+	Rematch       *Game     // Game that was played next, nil until it started
+	ForkOf        *Game     // Game this one continues from, nil otherwise
+	ForkPly       int       // Number of moves copied from ForkOf
+	Start         *Position // Position play started from, nil for an empty board
+	Match         *Match    // Series the game is part of, nil for single games
+	// Player that asked to take back their last move, nil while no takeback
+	// is pending
+	TakebackRequest *Participant
+	UnlimitedUndo   bool // Players take moves back without asking
 }
 
 func NewGame(id GameId) *Game {
+	return NewGameWithBoard(id, NewBoard())
+}
+
+// NewGameWithBoard creates a game played on the given (usually empty) board
+func NewGameWithBoard(id GameId, board *Board) *Game {
 	game := &Game{
 		Id:           id,
-		Board:        Board{},
+		Board:        *board,
 		Participants: orderedmap.New[ParticipantId, *Participant](),
 	}
 	return game
 }
 
-func (g *Game) Info() string {
-	if g.Winner != nil {
-		return "Player " + g.Winner.Name + " wins!"
+// ThreePlayerMinSide is the smallest board width and height three players
+// can play on, smaller boards fill up before anyone gets a line
+const ThreePlayerMinSide = 4
+
+// CheckThreePlayers reports whether a game of the variant on the board can be
+// played by three players
+func CheckThreePlayers(variant Variant, board *Board) error {
+	if variant != Classic {
+		return fmt.Errorf("%s games are played by two players", variant)
+	}
+	if board.Width() < ThreePlayerMinSide || board.Height() < ThreePlayerMinSide {
+		return fmt.Errorf("three players need a board of at least %dx%d", ThreePlayerMinSide, ThreePlayerMinSide)
 	}
+	return nil
+}
 
-	if g.Player1 == nil && g.Player2 == nil {
+func (g *Game) Info() string {
+	switch g.State {
+	case Lobby:
 		return "Waiting for players"
-	} else if g.Player2 == nil {
-		return "Waiting for player 2"
+	case WaitingForOpponent:
+		return g.waitingFor()
+	case Paused:
+		return "Paused: " + g.matchup()
+	case Finished, Abandoned:
+		return g.Outcome.String()
 	}
 
-	return "Playing " + g.Player1.Name + " vs " + g.Player2.Name
+	return "Playing " + g.matchup()
 }
 
 func (g *Game) PlayStatus() string {
-	if g.Winner != nil {
-		return "Game over! " + g.Winner.Name + " wins!"
+	switch g.State {
+	case Lobby:
+		return "Waiting for players"
+	case WaitingForOpponent:
+		return g.waitingFor()
+	case Paused:
+		return "Game paused"
+	case Finished, Abandoned:
+		return "Game over! " + g.Outcome.String()
 	}
 
-	if g.Player1 == nil {
-		return "Waiting for players"
-	} else if g.Player2 == nil {
-		return "Waiting for player 2"
+	return fmt.Sprintf("Current player: Player %d", g.CurrentPlayerValue())
+}
+
+// matchup lists the names of the seated players, e.g. "alice vs bob"
+func (g *Game) matchup() string {
+	var names []string
+	for _, p := range g.Seats() {
+		if p != nil {
+			names = append(names, p.Name)
+		}
 	}
+	return strings.Join(names, " vs ")
+}
 
-	displayName := "Player 1"
-	if g.CurrentPlayer == g.Player2 {
-		displayName = "Player 2"
+func (g *Game) waitingFor() string {
+	for i, p := range g.Seats() {
+		if p == nil {
+			return fmt.Sprintf("Waiting for player %d", i+1)
+		}
 	}
-	return "Current player: " + displayName
+	return "Waiting for players"
 }
 
 // Returns true if the client that joined is a player
 func (g *Game) Join(clientId ParticipantId, name string) bool {
-	if g.Player1 != nil && g.Player1.Id == clientId || g.Player2 != nil && g.Player2.Id == clientId {
-		return false
+	for _, p := range g.Seats() {
+		if p != nil && p.Id == clientId {
+			p.Connected = true
+			return false
+		}
 	}
 
-	if g.Player1 == nil {
+	switch g.State {
+	case Lobby:
 		g.Player1 = g.addParticipant(clientId, name, true)
+		g.transition(WaitingForOpponent)
 		return true
-	}
-
-	if g.Player2 == nil {
-		g.Player2 = g.addParticipant(clientId, name, true)
-		g.CurrentPlayer = g.Player1
+	case WaitingForOpponent:
+		player := g.addParticipant(clientId, name, true)
+		if g.Player2 == nil {
+			g.Player2 = player
+		} else {
+			g.Player3 = player
+		}
+		if g.seated() {
+			g.start()
+		}
 		return true
 	}
 
@@ -97,6 +169,15 @@ func (g *Game) Join(clientId ParticipantId, name string) bool {
 	return false
 }
 
+// JoinBot seats a computer player, returns true if it got a seat
+func (g *Game) JoinBot(id ParticipantId, name string) bool {
+	joined := g.Join(id, name)
+	if p, exists := g.Participants.Get(id); exists {
+		p.Bot = true
+	}
+	return joined
+}
+
 func (g *Game) addParticipant(id ParticipantId, name string, isPlayer bool) *Participant {
 	participant := &Participant{Id: id, Name: name, Player: isPlayer, Connected: true}
 	g.Participants.Set(participant.Id, participant)
@@ -105,24 +186,44 @@ func (g *Game) addParticipant(id ParticipantId, name string, isPlayer bool) *Par
 
 // func (g *Game) PlayMove(player int, index int, c chan<- GameId) error {
 func (g *Game) PlayMove(player int, index int) error {
-	if g.GameOver() {
+	return g.PlayPiece(player, index, g.Rules().Piece(player))
+}
+
+// PlayPiece plays a move that places the given piece, which only differs from
+// the player's own piece in variants like wild
+func (g *Game) PlayPiece(player int, index int, piece int) error {
+	switch g.State {
+	case Lobby, WaitingForOpponent:
+		return errors.New("Game has not started yet")
+	case Paused:
+		return errors.New("The game is paused")
+	case Finished, Abandoned:
 		return errors.New("The game has already ended")
 	}
-	if !g.Started() {
-		return errors.New("Game has not started yet")
+	if g.CheckTime() {
+		return errors.New("Out of time")
 	}
-	if g.Board.GetCell(index) != 0b00 {
-		return errors.New("Cell not empty")
+
+	rules := g.Rules()
+	move := Move{Player: player, Cell: index, Piece: piece, Ply: len(g.Moves) + 1}
+	if err := rules.Legal(g, move); err != nil {
+		return err
 	}
-	if (player == 1 && g.CurrentPlayer != g.Player1) || (player == 2 && g.CurrentPlayer != g.Player2) {
+	if player != g.CurrentPlayerValue() {
 		return errors.New("Not your turn")
 	}
-	if g.BoardFull() {
-		return errors.New("The board is full")
-	}
 
-	g.History = append(g.History, g.Board)
-	g.Board.setCell(index, player)
+	if err := rules.Apply(g, move); err != nil {
+		return err
+	}
+	move.Time = g.now()
+	g.Moves = append(g.Moves, move)
+	g.pressClock(player)
+	if !g.AgreedToDraw(g.seat(player)) {
+		// Playing on declines a pending offer
+		g.DrawOffers = nil
+	}
+	g.TakebackRequest = nil
 
 	// if c != nil {
 	// 	defer func() {
@@ -130,15 +231,30 @@ func (g *Game) PlayMove(player int, index int) error {
 	// 	}()
 	// }
 
-	if g.CheckWinner() {
-		g.Winner = g.CurrentPlayer
-		return nil
+	if outcome := rules.Outcome(g); outcome != nil {
+		outcome.Move = g.LastMove()
+		return g.end(outcome)
 	}
 
-	if g.CurrentPlayer == g.Player1 {
-		g.CurrentPlayer = g.Player2
-	} else {
-		g.CurrentPlayer = g.Player1
+	g.CurrentPlayer = g.seat(rules.SideToMove(g))
+	g.startClock()
+	return nil
+}
+
+// Rules returns the rules of the game's variant
+func (g *Game) Rules() Rules {
+	return g.Variant.Rules()
+}
+
+// seat returns the participant playing with the given cell value
+func (g *Game) seat(player int) *Participant {
+	switch player {
+	case 0b01:
+		return g.Player1
+	case 0b10:
+		return g.Player2
+	case 0b11:
+		return g.Player3
 	}
 	return nil
 }
@@ -151,56 +267,252 @@ func (g *Game) GetCell(index int) *Cell {
 	}
 }
 
+// BoardFull reports whether the player to move has no move left
 func (g *Game) BoardFull() bool {
-	for i := 0; i < 9; i++ {
-		if g.Board.GetCell(i) == 0b00 {
-			return false
-		}
+	return len(g.Rules().LegalMoves(g)) == 0
+}
+
+// CheckWinner returns the line that decided the game on the board or nil if
+// the game goes on
+func (g *Game) CheckWinner() *Line {
+	if outcome := g.Rules().Outcome(g); outcome != nil {
+		return outcome.Line
 	}
-	return true
+	return nil
 }
 
-func (g *Game) CheckWinner() bool {
-	// Horizontal
-	for i := 0; i < 9; i++ {
-		row := g.Board.GetCell(i) & g.Board.GetCell(i+1) & g.Board.GetCell(i+2)
-		if row == 0b01 || row == 0b10 {
-			return true
-		}
+// WinningLine returns the line that decided a won game
+func (g *Game) WinningLine() *Line {
+	if g.Outcome == nil {
+		return nil
+	}
+	return g.Outcome.Line
+}
+
+// func (g *Game) CurrentPlayer() Participant {
+// 	return *g.currentPlayer
+// }
+
+func (g *Game) GameOver() bool {
+	return g.State == Finished || g.State == Abandoned
+}
+
+// Winner returns the participant that won the game, if any
+func (g *Game) Winner() *Participant {
+	if g.Outcome == nil {
+		return nil
+	}
+	return g.Outcome.Winner
+}
+
+// Resign ends the game with the other players winning
+func (g *Game) Resign(player *Participant) error {
+	return g.forfeit(player, Resignation, player.Name+" resigned")
+}
+
+// TimeOut ends the game with the other players winning because the given
+// player ran out of time
+func (g *Game) TimeOut(player *Participant) error {
+	return g.forfeit(player, TimeoutForfeit, player.Name+" ran out of time")
+}
+
+// OfferDraw offers the other players a draw, which agrees to it when one of
+// them offered it first
+func (g *Game) OfferDraw(player *Participant) error {
+	if err := g.checkDrawOffer(player); err != nil {
+		return err
+	}
+	if g.AgreedToDraw(player) {
+		return errors.New("You already offered a draw")
+	}
+	g.DrawOffers = append(g.DrawOffers, player)
+	if len(g.DrawOffers) < g.PlayerCount() {
+		return nil
 	}
+	// Every player agreed
+	return g.end(&Outcome{Kind: Draw, Reason: "Draw agreed"})
+}
+
+// AcceptDraw agrees to the draw offer of another player
+func (g *Game) AcceptDraw(player *Participant) error {
+	if g.DrawOffer() == nil {
+		return errors.New("No draw was offered")
+	}
+	return g.OfferDraw(player)
+}
+
+// DeclineDraw turns down the pending draw offer
+func (g *Game) DeclineDraw(player *Participant) error {
+	if err := g.checkDrawOffer(player); err != nil {
+		return err
+	}
+	if g.DrawOffer() == nil {
+		return errors.New("No draw was offered")
+	}
+	if g.AgreedToDraw(player) {
+		return errors.New("You cannot decline your own offer")
+	}
+	g.DrawOffers = nil
+	return nil
+}
 
-	// Vertical
-	for i := 0; i < 3; i++ {
-		column := g.Board.GetCell(i) & g.Board.GetCell(i+3) & g.Board.GetCell(i+6)
-		if column == 0b01 || column == 0b10 {
+// DrawOffer returns the player that offered a draw, nil if there is no
+// pending offer
+func (g *Game) DrawOffer() *Participant {
+	if len(g.DrawOffers) == 0 {
+		return nil
+	}
+	return g.DrawOffers[0]
+}
+
+// AgreedToDraw reports whether the player offered or accepted the pending
+// draw offer
+func (g *Game) AgreedToDraw(player *Participant) bool {
+	for _, p := range g.DrawOffers {
+		if p == player {
 			return true
 		}
 	}
+	return false
+}
 
-	// Diagonal
-	diagonal1 := g.Board.GetCell(0) & g.Board.GetCell(4) & g.Board.GetCell(8)
-	if diagonal1 == 0b01 || diagonal1 == 0b10 {
-		return true
+func (g *Game) checkDrawOffer(player *Participant) error {
+	if g.GameOver() {
+		return errors.New("The game has already ended")
 	}
+	if !g.Started() {
+		return errors.New("Game has not started yet")
+	}
+	if g.PlayerValue(player) == 0 {
+		return errors.New("Not a player in this game")
+	}
+	return nil
+}
 
-	diagonal2 := g.Board.GetCell(2) & g.Board.GetCell(4) & g.Board.GetCell(6)
-	if diagonal2 == 0b01 || diagonal2 == 0b10 {
-		return true
+// Abandon ends the game without a winner
+func (g *Game) Abandon(reason string) error {
+	if g.GameOver() {
+		return errors.New("The game has already ended")
 	}
+	return g.end(&Outcome{Kind: Abandonment, Reason: reason})
+}
 
-	return false
+func (g *Game) forfeit(player *Participant, kind OutcomeKind, reason string) error {
+	if g.GameOver() {
+		return errors.New("The game has already ended")
+	}
+	if !g.Started() {
+		return errors.New("Game has not started yet")
+	}
+
+	value := g.PlayerValue(player)
+	if value == 0 {
+		return errors.New("Not a player in this game")
+	}
+	var winner *Participant
+	if !g.ThreePlayers {
+		// With three players the game ends all the same, the other two
+		// share the win (1/2-0-1/2) instead of one of them winning
+		winner = g.seat(Opponent(value))
+	}
+
+	return g.end(&Outcome{Kind: kind, Winner: winner, Loser: player, Reason: reason})
 }
 
-// func (g *Game) CurrentPlayer() Participant {
-// 	return *g.currentPlayer
-// }
+// start hands the first move to player 1 once every seat is taken, or to
+// whoever is next in games that continue from a position
+func (g *Game) start() error {
+	if err := g.transition(InProgress); err != nil {
+		return err
+	}
+	g.CurrentPlayer = g.seat(g.Rules().SideToMove(g))
+	return nil
+}
 
-func (g *Game) GameOver() bool {
-	return g.Winner != nil || g.BoardFull()
+func (g *Game) end(outcome *Outcome) error {
+	next := Finished
+	if outcome.Kind == Abandonment {
+		next = Abandoned
+	}
+	if err := g.transition(next); err != nil {
+		return err
+	}
+	g.Outcome = outcome
+	g.DrawOffers = nil
+	g.TakebackRequest = nil
+	return nil
 }
 
+// Started reports whether every seat was taken and play began
 func (g *Game) Started() bool {
-	return g.CurrentPlayer != nil
+	switch g.State {
+	case InProgress, Paused, Finished:
+		return true
+	case Abandoned:
+		return g.CurrentPlayer != nil
+	}
+	return false
+}
+
+// PlayerValue returns the cell value of a seated player (0b01 for player 1,
+// 0b10 for player 2 and 0b11 for player 3) or 0 for anyone else
+func (g *Game) PlayerValue(p *Participant) int {
+	switch {
+	case p == nil:
+		return 0
+	case p == g.Player1:
+		return 0b01
+	case p == g.Player2:
+		return 0b10
+	case p == g.Player3:
+		return 0b11
+	}
+	return 0
+}
+
+// SeatedPlayer returns the player seated with the participant id, nil for
+// spectators
+func (g *Game) SeatedPlayer(id ParticipantId) *Participant {
+	for _, p := range g.Seats() {
+		if p != nil && p.Id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// PlayerCount returns the number of seats of the game
+func (g *Game) PlayerCount() int {
+	if g.ThreePlayers {
+		return 3
+	}
+	return 2
+}
+
+// Seats returns the seated players in turn order, nil for empty seats
+func (g *Game) Seats() []*Participant {
+	seats := []*Participant{g.Player1, g.Player2, g.Player3}
+	return seats[:g.PlayerCount()]
+}
+
+// seated reports whether every seat of the game is taken
+func (g *Game) seated() bool {
+	for _, p := range g.Seats() {
+		if p == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// playerAfter returns the player that moves after the given one
+func (g *Game) playerAfter(player int) int {
+	return player%g.PlayerCount() + 1
+}
+
+// CurrentPlayerValue returns the cell value of the player to move
+func (g *Game) CurrentPlayerValue() int {
+	return g.PlayerValue(g.CurrentPlayer)
 }
 
 func (g *Game) Player1Name() string {
@@ -217,52 +529,53 @@ func (g *Game) Player2Name() string {
 	return g.Player2.Name
 }
 
-// Returns the last move played in format (player, cell)
-func (g *Game) LastMove() (int, int) {
-
-	if len(g.History) == 0 {
-		return -1, 1
+func (g *Game) Player3Name() string {
+	if g.Player3 == nil {
+		return ""
 	}
+	return g.Player3.Name
+}
 
-	// bin := func(i int) string {
-	// 	return fmt.Sprintf("%018b", i)
-	// }
-
-	lastBoard := g.History[len(g.History)-1].value
-	diff := g.Board.value ^ lastBoard
+// Returns the last move played or nil if no moves have been played yet
+func (g *Game) LastMove() *Move {
+	if len(g.Moves) == 0 {
+		return nil
+	}
+	return &g.Moves[len(g.Moves)-1]
+}
 
-	if diff&(diff-1) != 0 {
-		panic("More than 2 bits changed between boards")
+// BoardAt replays the move log and returns the board after the given number of
+// moves (0 is the starting position)
+func (g *Game) BoardAt(ply int) (Board, error) {
+	if ply < 0 || ply > len(g.Moves) {
+		return Board{}, errors.New("Move out of range")
+	}
+	if ply == len(g.Moves) {
+		return g.Board, nil
 	}
 
-	for i := 0; i < 2*9; i += 2 {
-		shifted := (diff >> i)
-		cellValue := shifted & 0b11
-		if cellValue != 0 {
-			// Found two adjacent bits differing
-			cellIndex := i / 2
-			player := cellValue
-			return player, cellIndex
+	board := g.startBoard()
+	for _, move := range g.Moves[:ply] {
+		if err := board.setCell(move.Cell, move.Piece); err != nil {
+			return Board{}, err
 		}
 	}
+	return board, nil
+}
 
-	return -1, -1
+// History returns the board before each move in the move log
+func (g *Game) History() []Board {
+	history := make([]Board, 0, len(g.Moves))
+	board := g.startBoard()
+	for _, move := range g.Moves {
+		history = append(history, board)
+		board.setCell(move.Cell, move.Piece)
+	}
+	return history
 }
 
 func (g *Game) Cells() <-chan *Cell {
-	ch := make(chan *Cell)
-	go func() {
-		for i := 0; i < 9; i++ {
-			cell := &Cell{
-				Symbol: g.Board.Symbol(uint(i)),
-				Index:  uint(i),
-				// GameId: g.Id,
-			}
-			ch <- cell
-		}
-		close(ch)
-	}()
-	return ch
+	return g.Board.Cells()
 }
 
 func (g *Game) Spectators() <-chan *Participant {
@@ -0,0 +1,218 @@
+package tictactoe
+
+import "sync"
+
+type Direction int
+
+const (
+	Horizontal Direction = iota
+	Vertical
+	Diagonal
+	AntiDiagonal
+	// Through the layers of a 3D board
+	Depth
+)
+
+// Row and column step for each direction
+var lineDirections = [4][2]int{
+	Horizontal:   {0, 1},
+	Vertical:     {1, 0},
+	Diagonal:     {1, 1},
+	AntiDiagonal: {1, -1},
+}
+
+func (d Direction) String() string {
+	switch d {
+	case Horizontal:
+		return "horizontal"
+	case Vertical:
+		return "vertical"
+	case Diagonal:
+		return "diagonal"
+	case AntiDiagonal:
+		return "anti-diagonal"
+	case Depth:
+		return "depth"
+	}
+	return "unknown"
+}
+
+// Line is a run of cells on a board, e.g. the one that won a game
+type Line struct {
+	Cells     []int     `json:"cells"`
+	Direction Direction `json:"direction"`
+}
+
+func (l *Line) Contains(index int) bool {
+	if l == nil {
+		return false
+	}
+	for _, cell := range l.Cells {
+		if cell == index {
+			return true
+		}
+	}
+	return false
+}
+
+// winMask is a line as a bitmask, only words first..last have bits set
+type winMask struct {
+	bits  bitboard
+	first int
+	last  int
+}
+
+func (m *winMask) ownedBy(player *bitboard) bool {
+	for i := m.first; i <= m.last; i++ {
+		if player[i]&m.bits[i] != m.bits[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// winTable holds every winning line of a board geometry as a bitmask
+type winTable struct {
+	lines []Line
+	masks []winMask
+	// Indices into lines/masks of every line going through a cell
+	byCell [][]int
+}
+
+type boardGeometry struct {
+	width, height, winLength int
+}
+
+var (
+	winTablesMu sync.Mutex
+	winTables   = map[boardGeometry]*winTable{}
+)
+
+// winTableFor returns the (cached) winning lines of a board geometry
+func winTableFor(width int, height int, winLength int) *winTable {
+	key := boardGeometry{width, height, winLength}
+	winTablesMu.Lock()
+	defer winTablesMu.Unlock()
+	if table, exists := winTables[key]; exists {
+		return table
+	}
+
+	table := &winTable{byCell: make([][]int, width*height)}
+	for i := 0; i < width*height; i++ {
+		row, col := i/width, i%width
+		for dir, step := range lineDirections {
+			endRow, endCol := row+step[0]*(winLength-1), col+step[1]*(winLength-1)
+			if endRow < 0 || endRow >= height || endCol < 0 || endCol >= width {
+				continue
+			}
+			line := Line{Cells: make([]int, winLength), Direction: Direction(dir)}
+			mask := winMask{first: boardWords, last: -1}
+			for n := range line.Cells {
+				cell := (row+step[0]*n)*width + col + step[1]*n
+				line.Cells[n] = cell
+				mask.bits.set(cell)
+				mask.first = min(mask.first, cell/64)
+				mask.last = max(mask.last, cell/64)
+				table.byCell[cell] = append(table.byCell[cell], len(table.lines))
+			}
+			table.lines = append(table.lines, line)
+			table.masks = append(table.masks, mask)
+		}
+	}
+
+	winTables[key] = table
+	return table
+}
+
+func (b *Board) winTable() *winTable {
+	if b.lines == nil {
+		b.lines = winTableFor(b.width, b.height, b.winLength)
+	}
+	return b.lines
+}
+
+// WinningLine returns the first line of WinLength cells owned by the same
+// player or nil if there is none
+func (b *Board) WinningLine() *Line {
+	table := b.winTable()
+	for p := range b.players {
+		if b.players[p].count() < b.winLength {
+			continue
+		}
+		for i := range table.masks {
+			if table.masks[i].ownedBy(&b.players[p]) {
+				return &table.lines[i]
+			}
+		}
+	}
+	return nil
+}
+
+// WinningLineThrough only checks the lines going through the given cell,
+// which is all that can change after a move is played there
+func (b *Board) WinningLineThrough(index int) *Line {
+	player := b.GetCell(index)
+	if player == 0b00 {
+		return nil
+	}
+	table := b.winTable()
+	for _, i := range table.byCell[index] {
+		if table.masks[i].ownedBy(&b.players[player-1]) {
+			return &table.lines[i]
+		}
+	}
+	return nil
+}
+
+// Drawn reports whether neither player can complete a line anymore, given the
+// player that moves next. A line stays open for a player while the opponent
+// hasn't played in it and the player still has enough moves left to fill it
+func (b *Board) Drawn(next int) bool {
+	return b.DrawnFor(next, 2)
+}
+
+// DrawnFor is Drawn for a game where the given number of players take turns
+func (b *Board) DrawnFor(next int, players int) bool {
+	table := b.winTable()
+	empty := b.Size() - b.MoveCount()
+	for p := 0; p < players; p++ {
+		// The player gets every players-th of the remaining moves, starting
+		// at their place in the rotation after next
+		turn := (p - (next - 1) + players) % players
+		movesLeft := empty / players
+		if turn < empty%players {
+			movesLeft++
+		}
+		var others bitboard
+		for o := 0; o < players; o++ {
+			if o == p {
+				continue
+			}
+			for i := range others {
+				others[i] |= b.players[o][i]
+			}
+		}
+		for i := range table.masks {
+			if others.intersects(&table.masks[i].bits) {
+				continue
+			}
+			owned := b.players[p].and(&table.masks[i].bits)
+			if b.winLength-owned.count() <= movesLeft {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Blocked reports whether every line holds both X and O, so that no line can
+// be completed with either piece anymore
+func (b *Board) Blocked() bool {
+	table := b.winTable()
+	for i := range table.masks {
+		if !b.players[0].intersects(&table.masks[i].bits) || !b.players[1].intersects(&table.masks[i].bits) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+)
+
+type MatchId int
+
+// Match is a best of N series between two players. Every game after the first
+// is a rematch of the one before, so the players take turns starting
+type Match struct {
+	Id     MatchId
+	BestOf int
+	// Games in the order they were played, the last one is being played
+	Games []*Game
+}
+
+// NewMatch makes the first game of a best of N series, N has to be odd so
+// that one side can clinch it
+func NewMatch(id MatchId, bestOf int, first *Game) (*Match, error) {
+	if bestOf < 1 || bestOf%2 == 0 {
+		return nil, fmt.Errorf("Invalid number of games %d, it has to be odd", bestOf)
+	}
+	if first.ThreePlayers {
+		return nil, errors.New("Matches are played by two players")
+	}
+	if first.Started() {
+		return nil, errors.New("The game has already started")
+	}
+	match := &Match{Id: id, BestOf: bestOf, Games: []*Game{first}}
+	first.Match = match
+	return match, nil
+}
+
+// Current returns the game that is being played, or the last one once the
+// match is over
+func (m *Match) Current() *Game {
+	return m.Games[len(m.Games)-1]
+}
+
+// Players returns the ids of both sides in the seats of the first game, empty
+// until it started
+func (m *Match) Players() []ParticipantId {
+	first := m.Games[0]
+	if !first.Started() {
+		return nil
+	}
+	return []ParticipantId{first.Player1.Id, first.Player2.Id}
+}
+
+// Score adds up the points a player scored in the games of the match
+func (m *Match) Score(id ParticipantId) float64 {
+	points := 0.0
+	for _, game := range m.Games {
+		points += game.Points(id)
+	}
+	return points
+}
+
+// Winner returns the id of the player that clinched the match, more than half
+// of the points can't be caught up with anymore
+func (m *Match) Winner() (ParticipantId, bool) {
+	for _, id := range m.Players() {
+		if m.Score(id) > float64(m.BestOf)/2 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// Over reports whether the match was decided, one of its games was abandoned,
+// or every game was played without anyone clinching it because of draws
+func (m *Match) Over() bool {
+	if _, clinched := m.Winner(); clinched {
+		return true
+	}
+	current := m.Current()
+	if current.State == Abandoned {
+		// Nobody came back to play, the rest of the games wouldn't be either
+		return true
+	}
+	return len(m.Games) >= m.BestOf && current.GameOver()
+}
+
+// NextGame starts the next game of the match once the current one is over,
+// with the other player moving first
+func (m *Match) NextGame() (*Game, error) {
+	current := m.Current()
+	if !current.GameOver() {
+		return nil, errors.New("The current game is still being played")
+	}
+	if m.Over() {
+		return nil, errors.New("The match is over")
+	}
+	next, err := current.newRematch()
+	if err != nil {
+		return nil, err
+	}
+	current.Rematch = next
+	next.Match = m
+	m.Games = append(m.Games, next)
+	return next, nil
+}
+
+// Info describes the state of the match for the game list, e.g.
+// "alice 2 - 1 bob (best of 5)"
+func (m *Match) Info() string {
+	players := m.Players()
+	if players == nil {
+		return fmt.Sprintf("Best of %d, waiting for players", m.BestOf)
+	}
+	first := m.Games[0]
+	score := fmt.Sprintf("%s %s - %s %s", first.Player1.Name, FormatPoints(m.Score(players[0])), FormatPoints(m.Score(players[1])), first.Player2.Name)
+	if !m.Over() {
+		return fmt.Sprintf("%s (best of %d)", score, m.BestOf)
+	}
+	if winner, clinched := m.Winner(); clinched {
+		loser := players[0]
+		if loser == winner {
+			loser = players[1]
+		}
+		return fmt.Sprintf("%s wins the match %s-%s", m.Current().SeatedPlayer(winner).Name, FormatPoints(m.Score(winner)), FormatPoints(m.Score(loser)))
+	}
+	if m.Current().State == Abandoned {
+		return "Match abandoned " + score
+	}
+	return "Match drawn " + score
+}
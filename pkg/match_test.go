@@ -0,0 +1,59 @@
+package tictactoe
+
+import "testing"
+
+// matchAfterFirstGame plays a best of 5 where alice wins the first game
+func matchAfterFirstGame(t *testing.T) *Match {
+	match, err := NewMatch(1, 5, NewGame(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := match.Current()
+	game.Join("alice", "alice")
+	game.Join("bob", "bob")
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		if err := game.PlayMove(1<<(i%2), cell); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !game.GameOver() || match.Over() {
+		t.Fatalf("first game is %s, match over %v", game.State, match.Over())
+	}
+	return match
+}
+
+func TestMatchEndsWhenNextGameIsAbandoned(t *testing.T) {
+	match := matchAfterFirstGame(t)
+	next, err := match.NextGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := next.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err := next.Abandon("Nobody came back"); err != nil {
+		t.Fatal(err)
+	}
+	if !match.Over() {
+		t.Error("the match goes on after its game was abandoned")
+	}
+	if _, err := match.NextGame(); err == nil {
+		t.Error("another game followed the abandoned one")
+	}
+	if info, want := match.Info(), "Match abandoned alice 1 - 0 bob"; info != want {
+		t.Errorf("Info = %q, want %q", info, want)
+	}
+}
+
+func TestMatchEndsWhenFirstGameIsAbandoned(t *testing.T) {
+	match, err := NewMatch(1, 3, NewGame(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := match.Current().Abandon("Nobody joined"); err != nil {
+		t.Fatal(err)
+	}
+	if !match.Over() {
+		t.Error("the match goes on after nobody showed up")
+	}
+}
@@ -1,4 +1,30 @@
 package tictactoe
 
+import (
+	"fmt"
+	"time"
+)
+
+// Move is a single placement in a game's move log
 type Move struct {
+	// Cell value of the player that moved (0b01 for X, 0b10 for O)
+	Player int `json:"player"`
+	Cell   int `json:"cell"`
+	// Cell value of the piece placed, the player's own one unless the rules
+	// say otherwise
+	Piece int `json:"piece"`
+	// 1-based position of the move in the game
+	Ply  int       `json:"ply"`
+	Time time.Time `json:"time"`
+}
+
+// MoveLog is the ordered list of moves played in a game
+type MoveLog []Move
+
+func (m Move) Symbol() string {
+	return PlayerSymbol(m.Piece)
+}
+
+func (m Move) String() string {
+	return fmt.Sprintf("%d. %s at %d", m.Ply, m.Symbol(), m.Cell)
 }
@@ -0,0 +1,397 @@
+package tictactoe
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Games are written in a PGN like notation: optional [Name "value"] headers
+// followed by numbered moves and a result, e.g.
+//
+//	[X "alice"]
+//	[O "bob"]
+//	[Board "3x3"]
+//	[WinLength "3"]
+//	[Result "1-0"]
+//
+//	1. b2 a1 2. c3 a3 3. a2 b1 4. c1 1-0
+//
+// Cells are named by column letter (a is the leftmost column) and row number
+// (1 is the top row). Columns after z continue with aa, ab and so on. Results
+// are "1-0" when X wins, "0-1" when O wins, "1/2-1/2" for draws and "*" for
+// games without a result.
+//
+// Three player games add [Players "3"] and [Y "name"] headers and score every
+// player in their results, e.g. "0-0-1" when Y wins or "1/3-1/3-1/3" for
+// draws. A player that forfeits scores 0 and the other two 1/2 each.
+//
+// Games that start from a set up position record it in a [Setup] header with
+// the rows of the board from the top and the player that moves first, e.g.
+// [Setup "X../.O./... X"].
+
+const (
+	ResultXWins      = "1-0"
+	ResultOWins      = "0-1"
+	ResultDraw       = "1/2-1/2"
+	ResultInProgress = "*"
+)
+
+const notationDate = "2006.01.02"
+
+// CellName returns the coordinate of a cell, e.g. "b2" for the center of a
+// 3x3 board
+func (b *Board) CellName(index int) string {
+	return fmt.Sprintf("%s%d", columnName(index%b.width), index/b.width+1)
+}
+
+// columnName counts columns like spreadsheets do: a to z, then aa, ab...
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('a'+(col-1)%26)) + name
+	}
+	return name
+}
+
+var cellNamePattern = regexp.MustCompile(`^([a-z]{1,3})([0-9]+)$`)
+
+// ParseCell returns the index of a cell coordinate such as "b2"
+func (b *Board) ParseCell(name string) (int, error) {
+	match := cellNamePattern.FindStringSubmatch(strings.ToLower(name))
+	if match == nil {
+		return -1, fmt.Errorf("invalid cell %q", name)
+	}
+	col := -1
+	for _, letter := range match[1] {
+		col = (col+1)*26 + int(letter-'a')
+	}
+	row, _ := strconv.Atoi(match[2])
+	row--
+	if col >= b.width || row < 0 || row >= b.height {
+		return -1, fmt.Errorf("cell %q is not on a %dx%d board", name, b.width, b.height)
+	}
+	return row*b.width + col, nil
+}
+
+// Result returns the notation result tag of the game
+func (g *Game) Result() string {
+	if g.ThreePlayers {
+		return g.threePlayerResult(g.Outcome)
+	}
+	switch {
+	case g.Outcome == nil || g.Outcome.Kind == Abandonment:
+		return ResultInProgress
+	case g.Outcome.Winner == nil:
+		return ResultDraw
+	case g.Outcome.Winner == g.Player1:
+		return ResultXWins
+	}
+	return ResultOWins
+}
+
+func (g *Game) threePlayerResult(o *Outcome) string {
+	if o == nil || o.Kind == Abandonment {
+		return ResultInProgress
+	}
+	scores := make([]string, 0, 3)
+	for _, p := range g.Seats() {
+		switch {
+		case o.Winner == nil && o.Loser == nil:
+			scores = append(scores, "1/3")
+		case o.Winner == p:
+			scores = append(scores, "1")
+		case o.Winner == nil && o.Loser != p:
+			scores = append(scores, "1/2")
+		default:
+			scores = append(scores, "0")
+		}
+	}
+	return strings.Join(scores, "-")
+}
+
+// Notation writes the game and its move log in text notation
+func (g *Game) Notation() string {
+	var sb strings.Builder
+	header := func(name string, value string) {
+		fmt.Fprintf(&sb, "[%s %q]\n", name, value)
+	}
+
+	header("Event", fmt.Sprintf("Game %d", g.Id))
+	if len(g.Moves) > 0 && !g.Moves[0].Time.IsZero() {
+		header("Date", g.Moves[0].Time.Format(notationDate))
+	}
+	header("X", notationName(g.Player1))
+	header("O", notationName(g.Player2))
+	if g.ThreePlayers {
+		header("Y", notationName(g.Player3))
+		header("Players", "3")
+	}
+	if g.Variant != Classic {
+		header("Variant", strings.ToLower(g.Variant.String()))
+	}
+	header("Board", fmt.Sprintf("%dx%d", g.Board.Width(), g.Board.Height()))
+	header("WinLength", strconv.Itoa(g.Board.WinLength()))
+	if g.EarlyDraws {
+		header("EarlyDraws", "true")
+	}
+	if g.Start != nil {
+		header("Setup", g.Start.String())
+	}
+	if g.Clocks != nil {
+		header("TimeControl", g.Clocks.Control.String())
+	}
+	header("Result", g.Result())
+	if g.Outcome != nil {
+		header("Termination", strings.ToLower(g.Outcome.Kind.String()))
+		header("Reason", g.Outcome.Reason)
+	}
+	sb.WriteString("\n")
+
+	// A move number per round of turns
+	round := g.PlayerCount()
+	for i, move := range g.Moves {
+		if i%round == 0 {
+			fmt.Fprintf(&sb, "%d. ", i/round+1)
+		}
+		sb.WriteString(g.Board.CellName(move.Cell))
+		if move.Piece != g.Rules().Piece(move.Player) {
+			// Variants like wild let players pick their piece
+			sb.WriteString("=" + move.Symbol())
+		}
+		sb.WriteString(" ")
+	}
+	sb.WriteString(g.Result() + "\n")
+	return sb.String()
+}
+
+func notationName(p *Participant) string {
+	if p == nil {
+		return "?"
+	}
+	return p.Name
+}
+
+var (
+	headerPattern     = regexp.MustCompile(`^\[(\w+)\s+"((?:[^"\\]|\\.)*)"\]$`)
+	moveNumberPattern = regexp.MustCompile(`^[0-9]+\.+$`)
+)
+
+// ParseNotation rebuilds a game from text notation by replaying its moves
+func ParseNotation(text string) (*Game, error) {
+	headers := map[string]string{}
+	var tokens []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := headerPattern.FindStringSubmatch(line); match != nil {
+			value, err := strconv.Unquote(`"` + match[2] + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid header %s: %w", match[1], err)
+			}
+			headers[match[1]] = value
+			continue
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+
+	board, err := notationBoard(headers)
+	if err != nil {
+		return nil, err
+	}
+	// Only the day is recorded, moves without one get no time at all
+	var played time.Time
+	if date := headerOr(headers, "Date", ""); date != "" {
+		if played, err = time.Parse(notationDate, date); err != nil {
+			return nil, fmt.Errorf("invalid date %q", date)
+		}
+	}
+	variant, err := ParseVariant(headerOr(headers, "Variant", Classic.String()))
+	if err != nil {
+		return nil, err
+	}
+	if fixed := variant.board(); fixed != nil {
+		if _, exists := headers["Board"]; !exists {
+			board = fixed
+		}
+	}
+	if err := variant.checkBoard(board); err != nil {
+		return nil, err
+	}
+	game := NewGameWithBoard(0, board)
+	game.Variant = variant
+	game.EarlyDraws = headers["EarlyDraws"] == "true"
+	switch players := headerOr(headers, "Players", "2"); players {
+	case "2":
+	case "3":
+		if err := CheckThreePlayers(variant, board); err != nil {
+			return nil, err
+		}
+		game.ThreePlayers = true
+	default:
+		return nil, fmt.Errorf("invalid number of players %q", players)
+	}
+	for i, symbol := range []string{"X", "O", "Y"}[:game.PlayerCount()] {
+		name := headerOr(headers, symbol, symbol)
+		id := ParticipantId(name)
+		if _, taken := game.Participants.Get(id); taken {
+			id += ParticipantId("-" + symbol)
+		}
+		player := game.addParticipant(id, name, true)
+		player.Connected = false
+		switch i {
+		case 0:
+			game.Player1 = player
+		case 1:
+			game.Player2 = player
+		case 2:
+			game.Player3 = player
+		}
+	}
+	if tc, exists := headers["TimeControl"]; exists && tc != "-" {
+		control, err := ParseTimeControl(tc)
+		if err != nil {
+			return nil, err
+		}
+		game.Clocks = NewClocks(control, nil)
+	}
+	if setup, exists := headers["Setup"]; exists {
+		position, err := ParsePosition(board, setup)
+		if err != nil {
+			return nil, err
+		}
+		if err := game.SetStart(position); err != nil {
+			return nil, err
+		}
+	}
+	game.State = WaitingForOpponent
+	if err := game.start(); err != nil {
+		return nil, err
+	}
+
+	result := headerOr(headers, "Result", ResultInProgress)
+	for i, token := range tokens {
+		if moveNumberPattern.MatchString(token) {
+			continue
+		}
+		if isResult(token) {
+			if i != len(tokens)-1 {
+				return nil, errors.New("moves found after the result")
+			}
+			result = token
+			break
+		}
+		name, symbol, picked := strings.Cut(token, "=")
+		cell, err := game.Board.ParseCell(name)
+		if err != nil {
+			return nil, err
+		}
+		player := game.CurrentPlayerValue()
+		piece := game.Rules().Piece(player)
+		if picked {
+			if piece = symbolPlayer(symbol); piece < 0 {
+				return nil, fmt.Errorf("invalid piece %q", symbol)
+			}
+		}
+		if err := game.PlayPiece(player, cell, piece); err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", len(game.Moves)+1, token, err)
+		}
+	}
+	for i := range game.Moves {
+		game.Moves[i].Time = played
+	}
+
+	if err := game.applyResult(result, headers["Termination"], headers["Reason"]); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func notationBoard(headers map[string]string) (*Board, error) {
+	width, height := 3, 3
+	if size, exists := headers["Board"]; exists {
+		if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil {
+			return nil, fmt.Errorf("invalid board size %q", size)
+		}
+	}
+	winLength := min(width, height, 3)
+	if k, exists := headers["WinLength"]; exists {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid win length %q", k)
+		}
+		winLength = n
+	}
+	return NewBoardWithSize(width, height, winLength)
+}
+
+func headerOr(headers map[string]string, name string, fallback string) string {
+	if value := headers[name]; value != "" && value != "?" {
+		return value
+	}
+	return fallback
+}
+
+var threePlayerResultPattern = regexp.MustCompile(`^(?:1|0|1/2|1/3)-(?:1|0|1/2|1/3)-(?:1|0|1/2|1/3)$`)
+
+func isResult(token string) bool {
+	switch token {
+	case ResultXWins, ResultOWins, ResultDraw, ResultInProgress:
+		return true
+	}
+	return threePlayerResultPattern.MatchString(token)
+}
+
+// applyResult ends a replayed game the way its result says it ended when the
+// moves alone did not finish it
+func (g *Game) applyResult(result string, termination string, reason string) error {
+	if g.GameOver() {
+		if g.Result() != result {
+			return fmt.Errorf("result %s does not match the moves (%s)", result, g.Result())
+		}
+		return nil
+	}
+
+	var winner, loser *Participant
+	switch {
+	case result == ResultInProgress:
+		if termination == "abandoned" {
+			return g.Abandon(reason)
+		}
+		return nil
+	case result == ResultDraw && !g.ThreePlayers, result == "1/3-1/3-1/3" && g.ThreePlayers:
+		if reason == "" {
+			reason = "Draw agreed"
+		}
+		return g.end(&Outcome{Kind: Draw, Reason: reason})
+	case g.ThreePlayers:
+		// Only a forfeit ends a three player game without a line
+		for _, p := range g.Seats() {
+			if g.threePlayerResult(&Outcome{Kind: Resignation, Loser: p}) == result {
+				loser = p
+			}
+		}
+		if loser == nil {
+			return fmt.Errorf("invalid result %q", result)
+		}
+	case result == ResultXWins:
+		winner, loser = g.Player1, g.Player2
+	case result == ResultOWins:
+		winner, loser = g.Player2, g.Player1
+	default:
+		return fmt.Errorf("invalid result %q", result)
+	}
+
+	kind := Resignation
+	if termination == strings.ToLower(TimeoutForfeit.String()) {
+		kind = TimeoutForfeit
+	}
+	if reason == "" {
+		reason = loser.Name + " resigned"
+	}
+	return g.end(&Outcome{Kind: kind, Winner: winner, Loser: loser, Reason: reason})
+}
@@ -0,0 +1,66 @@
+package tictactoe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCellNamesRoundTrip(t *testing.T) {
+	board, _ := NewBoardWithSize(MaxBoardCells, 1, 3)
+	for i := 0; i < board.Size(); i++ {
+		name := board.CellName(i)
+		if index, err := board.ParseCell(name); err != nil || index != i {
+			t.Fatalf("ParseCell(%q) = %d, %v, want %d", name, index, err, i)
+		}
+	}
+	for index, name := range map[int]string{0: "a1", 25: "z1", 26: "aa1", 27: "ab1", 52: "ba1"} {
+		if got := board.CellName(index); got != name {
+			t.Errorf("CellName(%d) = %q, want %q", index, got, name)
+		}
+	}
+}
+
+func TestNotationRoundTripOnWideBoard(t *testing.T) {
+	board, _ := NewBoardWithSize(30, 8, 4)
+	game := NewGameWithBoard(1, board)
+	game.Join("alice", "alice")
+	game.Join("bob", "bob")
+	// X wins along the top row, beyond column z
+	for _, cell := range []int{26, 56, 27, 57, 28, 58, 29} {
+		if err := game.PlayMove(game.CurrentPlayerValue(), cell); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	imported, err := ParseNotation(game.Notation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Board != game.Board || imported.Result() != ResultXWins {
+		t.Errorf("imported %s with result %s, want %s with %s", imported.Board.Key(), imported.Result(), game.Board.Key(), ResultXWins)
+	}
+}
+
+func TestNotationKeepsTheDate(t *testing.T) {
+	text := "[Date \"2024.08.01\"]\n[X \"alice\"]\n[O \"bob\"]\n\n1. b2 a1 *\n"
+	game, err := ParseNotation(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range game.Moves {
+		if got := move.Time.Format(notationDate); got != "2024.08.01" {
+			t.Errorf("move %d was played on %s, want 2024.08.01", move.Ply, got)
+		}
+	}
+	if !strings.Contains(game.Notation(), "[Date \"2024.08.01\"]") {
+		t.Errorf("export lost the date:\n%s", game.Notation())
+	}
+
+	undated, err := ParseNotation("1. b2 a1 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(undated.Notation(), "[Date") {
+		t.Errorf("export made up a date:\n%s", undated.Notation())
+	}
+}
@@ -0,0 +1,62 @@
+package tictactoe
+
+type OutcomeKind int
+
+const (
+	Win OutcomeKind = iota + 1
+	Draw
+	Resignation
+	TimeoutForfeit
+	Abandonment
+)
+
+func (k OutcomeKind) String() string {
+	switch k {
+	case Win:
+		return "Win"
+	case Draw:
+		return "Draw"
+	case Resignation:
+		return "Resignation"
+	case TimeoutForfeit:
+		return "Timeout"
+	case Abandonment:
+		return "Abandoned"
+	}
+	return "Unknown"
+}
+
+// Outcome describes how a finished game ended
+type Outcome struct {
+	Kind OutcomeKind
+	// Winner is nil for draws, abandoned games and forfeits of three player
+	// games
+	Winner *Participant
+	// Loser is the player that resigned, ran out of time or completed a line
+	// in a misère variant
+	Loser  *Participant
+	Reason string
+	// Move that decided the game, nil if it didn't end on a move
+	Move *Move
+	// Line that won the game, only set for wins. For ultimate games it is the
+	// line of small boards on the meta-board
+	Line *Line
+}
+
+func (o *Outcome) String() string {
+	switch o.Kind {
+	case Win:
+		return "Player " + o.Winner.Name + " wins!"
+	case Draw:
+		return "Draw! " + o.Reason
+	case Resignation, TimeoutForfeit:
+		if o.Winner == nil {
+			// Three player games end too, the other two players share the win
+			return "Player " + o.Loser.Name + " loses! " + o.Reason
+		}
+		return "Player " + o.Winner.Name + " wins! " + o.Reason
+	case Abandonment:
+		return "Game abandoned. " + o.Reason
+	}
+	return o.Reason
+}
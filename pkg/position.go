@@ -0,0 +1,126 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Position is a board set up by hand and the player that moves first from it
+type Position struct {
+	Board  Board `json:"board"`
+	ToMove int   `json:"toMove"` // Cell value of the player that moves next
+}
+
+// NewPosition sets up a position from the symbols of every cell, "X", "O" or
+// "" for empty ones
+func NewPosition(board *Board, cells []string, toMove int) (*Position, error) {
+	if len(cells) != board.Size() {
+		return nil, fmt.Errorf("Expected %d cells, got %d", board.Size(), len(cells))
+	}
+	position := &Position{Board: board.empty(), ToMove: toMove}
+	for i, symbol := range cells {
+		if symbol == "" {
+			continue
+		}
+		if err := position.Board.setCell(i, symbolPlayer(symbol)); err != nil {
+			return nil, fmt.Errorf("Invalid piece %q on %s", symbol, board.CellName(i))
+		}
+	}
+	return position, nil
+}
+
+// Check reports whether a two player game can be played from the position:
+// the players took turns placing their pieces, the right one moves next and
+// nobody completed a line yet
+func (p *Position) Check() error {
+	b := &p.Board
+	if p.ToMove != 0b01 && p.ToMove != 0b10 {
+		return errors.New("X or O has to move next")
+	}
+	if b.players[2].count() > 0 {
+		return errors.New("Y only plays in three player games")
+	}
+	x, o := b.players[0].count(), b.players[1].count()
+	switch {
+	case x > o+1 || o > x+1:
+		return fmt.Errorf("X has %d pieces and O %d, the players take turns", x, o)
+	case x > o && p.ToMove != 0b10:
+		return errors.New("O moves next, X has placed one piece more")
+	case o > x && p.ToMove != 0b01:
+		return errors.New("X moves next, O has placed one piece more")
+	}
+	if line := b.WinningLine(); line != nil {
+		return fmt.Errorf("%s already has %d in a row", PlayerSymbol(b.GetCell(line.Cells[0])), b.WinLength())
+	}
+	if b.Full() {
+		return errors.New("The board is full")
+	}
+	return nil
+}
+
+// String writes the rows of the board from the top and the player to move,
+// e.g. "X.O/.X./..O X"
+func (p *Position) String() string {
+	_, rows, _ := strings.Cut(p.Board.Key(), ":")
+	return rows + " " + PlayerSymbol(p.ToMove)
+}
+
+// ParsePosition reads a position written by Position.String for a board of
+// the given size
+func ParsePosition(board *Board, text string) (*Position, error) {
+	rows, toMove, found := strings.Cut(strings.TrimSpace(text), " ")
+	if !found {
+		return nil, fmt.Errorf("invalid position %q", text)
+	}
+	position := &Position{ToMove: symbolPlayer(toMove)}
+	key := fmt.Sprintf("%dx%d/%d:%s", board.Width(), board.Height(), board.WinLength(), rows)
+	if err := position.Board.UnmarshalText([]byte(key)); err != nil {
+		return nil, err
+	}
+	return position, nil
+}
+
+// SetStart has the game start from a position instead of an empty board
+func (g *Game) SetStart(position *Position) error {
+	if g.Started() || len(g.Moves) > 0 {
+		return errors.New("The game has already started")
+	}
+	if err := g.checkStart(position); err != nil {
+		return err
+	}
+	start := *position
+	g.Start = &start
+	g.Board = start.Board
+	return nil
+}
+
+func (g *Game) checkStart(position *Position) error {
+	if g.Variant != Classic && g.Variant != Misere {
+		return fmt.Errorf("%s games can't start from a set up position", g.Variant)
+	}
+	if g.ThreePlayers {
+		return errors.New("Set up positions are for two player games")
+	}
+	b := &position.Board
+	if b.Width() != g.Board.Width() || b.Height() != g.Board.Height() || b.WinLength() != g.Board.WinLength() {
+		return fmt.Errorf("The position is for a %dx%d board with %d in a row", b.Width(), b.Height(), b.WinLength())
+	}
+	return position.Check()
+}
+
+// startBoard returns the board the game started from
+func (g *Game) startBoard() Board {
+	if g.Start != nil {
+		return g.Start.Board
+	}
+	return g.Board.empty()
+}
+
+// firstPlayer returns the cell value of the player that moves first
+func (g *Game) firstPlayer() int {
+	if g.Start != nil {
+		return g.Start.ToMove
+	}
+	return 0b01
+}
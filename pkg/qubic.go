@@ -0,0 +1,235 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Side of the 4x4x4 Qubic cube
+const qubicSide = 4
+
+// Number of cells of the cube
+const qubicCells = qubicSide * qubicSide * qubicSide
+
+// QubicBoard is 3D tic-tac-toe on a 4x4x4 cube where 4 in a row along any of
+// its 76 lines wins. All 64 cells fit in one word per player.
+//
+// Cells are numbered layer by layer, row by row: layer*16 + row*4 + col
+type QubicBoard struct {
+	players [boardPlayers]uint64
+}
+
+type qubicLine struct {
+	mask uint64
+	line Line
+}
+
+// Every winning line of the cube and the lines going through each cell
+var qubicLines, qubicLinesByCell = buildQubicLines()
+
+func buildQubicLines() ([]qubicLine, [qubicCells][]int) {
+	var lines []qubicLine
+	var byCell [qubicCells][]int
+	inside := func(n int) bool { return n >= 0 && n < qubicSide }
+
+	for start := 0; start < qubicCells; start++ {
+		layer, row, col := QubicLocate(start)
+		// Only directions that point "forward" so that every line is found
+		// once, from its first cell
+		for dl := -1; dl <= 1; dl++ {
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					if dl < 0 || dl == 0 && (dr < 0 || dr == 0 && dc <= 0) {
+						continue
+					}
+					end := qubicSide - 1
+					if !inside(layer+dl*end) || !inside(row+dr*end) || !inside(col+dc*end) {
+						continue
+					}
+					l := qubicLine{line: Line{Cells: make([]int, qubicSide), Direction: qubicDirection(dl, dr, dc)}}
+					for n := range l.line.Cells {
+						cell := QubicIndex(layer+dl*n, row+dr*n, col+dc*n)
+						l.line.Cells[n] = cell
+						l.mask |= 1 << cell
+						byCell[cell] = append(byCell[cell], len(lines))
+					}
+					lines = append(lines, l)
+				}
+			}
+		}
+	}
+	return lines, byCell
+}
+
+// qubicDirection names a line by its direction within a layer, lines that go
+// through the layers are Depth lines
+func qubicDirection(dl int, dr int, dc int) Direction {
+	switch {
+	case dl != 0:
+		return Depth
+	case dr == 0:
+		return Horizontal
+	case dc == 0:
+		return Vertical
+	case dr == dc:
+		return Diagonal
+	}
+	return AntiDiagonal
+}
+
+// QubicLines returns the 76 winning lines of the cube
+func QubicLines() []Line {
+	lines := make([]Line, len(qubicLines))
+	for i := range qubicLines {
+		lines[i] = qubicLines[i].line
+	}
+	return lines
+}
+
+// QubicLocate returns the layer, row and column of a cell
+func QubicLocate(index int) (layer int, row int, col int) {
+	return index / (qubicSide * qubicSide), index / qubicSide % qubicSide, index % qubicSide
+}
+
+// QubicIndex is the inverse of QubicLocate
+func QubicIndex(layer int, row int, col int) int {
+	return (layer*qubicSide+row)*qubicSide + col
+}
+
+// newQubicFlatBoard returns the 4x16 board a Qubic game keeps its pieces on,
+// the four layers on top of each other
+func newQubicFlatBoard() *Board {
+	b, _ := NewBoardWithSize(qubicSide, qubicSide*qubicSide, qubicSide)
+	return b
+}
+
+// QubicBoardFrom reads the pieces of a cube from its flat 4x16 board
+func QubicBoardFrom(board *Board) (*QubicBoard, error) {
+	if board.Size() != qubicCells {
+		return nil, fmt.Errorf("a Qubic board has %d cells, not %d", qubicCells, board.Size())
+	}
+	q := &QubicBoard{}
+	for p := range q.players {
+		q.players[p] = board.players[p][0]
+	}
+	return q, nil
+}
+
+func (q *QubicBoard) GetCell(index int) int {
+	if index < 0 || index >= qubicCells {
+		return 0b00
+	}
+	for p := range q.players {
+		if q.players[p]&(1<<index) != 0 {
+			return p + 1
+		}
+	}
+	return 0b00
+}
+
+func (q *QubicBoard) Symbol(index int) string {
+	return PlayerSymbol(q.GetCell(index))
+}
+
+// Play puts a player's piece on an empty cell
+func (q *QubicBoard) Play(index int, player int) error {
+	if index < 0 || index >= qubicCells {
+		return errors.New("invalid cell")
+	}
+	if player < 0b01 || player > boardPlayers {
+		return errors.New("invalid player")
+	}
+	if q.GetCell(index) != 0b00 {
+		return errors.New("cell not empty")
+	}
+	q.players[player-1] |= 1 << index
+	return nil
+}
+
+func (q *QubicBoard) MoveCount() int {
+	n := 0
+	for _, pieces := range q.players {
+		n += bits.OnesCount64(pieces)
+	}
+	return n
+}
+
+func (q *QubicBoard) Full() bool {
+	return q.MoveCount() == qubicCells
+}
+
+// WinningLine returns the first line owned by one player or nil
+func (q *QubicBoard) WinningLine() *Line {
+	for _, pieces := range q.players {
+		for i := range qubicLines {
+			if pieces&qubicLines[i].mask == qubicLines[i].mask {
+				return &qubicLines[i].line
+			}
+		}
+	}
+	return nil
+}
+
+// WinningLineThrough only checks the lines through the given cell
+func (q *QubicBoard) WinningLineThrough(index int) *Line {
+	player := q.GetCell(index)
+	if player == 0b00 {
+		return nil
+	}
+	pieces := q.players[player-1]
+	for _, i := range qubicLinesByCell[index] {
+		if pieces&qubicLines[i].mask == qubicLines[i].mask {
+			return &qubicLines[i].line
+		}
+	}
+	return nil
+}
+
+// Drawn reports whether every line has pieces of both players in it, so
+// nobody can win anymore
+func (q *QubicBoard) Drawn() bool {
+	for i := range qubicLines {
+		mask := qubicLines[i].mask
+		if q.players[0]&mask == 0 || q.players[1]&mask == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LayerCells returns the cells of one layer of the cube
+func (q *QubicBoard) LayerCells(layer int) []*Cell {
+	cells := make([]*Cell, qubicSide*qubicSide)
+	for i := range cells {
+		index := layer*len(cells) + i
+		cells[i] = &Cell{Symbol: q.Symbol(index), Index: uint(index)}
+	}
+	return cells
+}
+
+// Layers returns the layer numbers of the cube, bottom first
+func (q *QubicBoard) Layers() []int {
+	layers := make([]int, qubicSide)
+	for i := range layers {
+		layers[i] = i
+	}
+	return layers
+}
+
+// NewQubicGame creates a 4x4x4 game, its Board holds the four layers of the
+// cube on top of each other
+func NewQubicGame(id GameId) *Game {
+	game := NewGameWithBoard(id, newQubicFlatBoard())
+	game.Variant = Qubic
+	return game
+}
+
+// QubicBoard returns the cube of a Qubic game, nil for other variants
+func (g *Game) QubicBoard() *QubicBoard {
+	if g.Variant != Qubic {
+		return nil
+	}
+	q, _ := QubicBoardFrom(&g.Board)
+	return q
+}
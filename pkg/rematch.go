@@ -0,0 +1,130 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// OfferRematch agrees to play the game again with the players moving one seat
+// up, so that in two player games X and O swap. Once every player agreed it
+// returns the new game
+func (g *Game) OfferRematch(player *Participant) (*Game, error) {
+	if !g.GameOver() || !g.Started() {
+		return nil, errors.New("Only finished games can be played again")
+	}
+	if g.Rematch != nil {
+		return nil, errors.New("The rematch has already started")
+	}
+	if g.Match != nil && !g.Match.Over() {
+		return nil, errors.New("The next game of the match starts on its own")
+	}
+	if g.PlayerValue(player) == 0 {
+		return nil, errors.New("Not a player in this game")
+	}
+	if g.AgreedToRematch(player) {
+		return nil, errors.New("You already asked for a rematch")
+	}
+	g.RematchOffers = append(g.RematchOffers, player)
+	if len(g.RematchOffers) < g.PlayerCount() {
+		return nil, nil
+	}
+
+	rematch, err := g.newRematch()
+	if err != nil {
+		return nil, err
+	}
+	g.Rematch = rematch
+	return rematch, nil
+}
+
+// AgreedToRematch reports whether the player asked for a rematch
+func (g *Game) AgreedToRematch(player *Participant) bool {
+	for _, p := range g.RematchOffers {
+		if p == player {
+			return true
+		}
+	}
+	return false
+}
+
+// newRematch starts a game with the same settings and rotated seats
+func (g *Game) newRematch() (*Game, error) {
+	board := g.startBoard()
+	rematch := NewGameWithBoard(0, &board)
+	rematch.Variant = g.Variant
+	rematch.ThreePlayers = g.ThreePlayers
+	rematch.EarlyDraws = g.EarlyDraws
+	rematch.Start = g.Start
+	if g.Clocks != nil {
+		rematch.Clocks = NewClocks(g.Clocks.Control, g.Clocks.Clock)
+	}
+	rematch.RematchOf = g
+
+	seats := g.Seats()
+	for i := range seats {
+		p := seats[(i+1)%len(seats)]
+		seated := rematch.addParticipant(p.Id, p.Name, true)
+		// Bots are always there, people have to come over first
+		seated.Bot, seated.Connected = p.Bot, p.Bot
+		switch i {
+		case 0:
+			rematch.Player1 = seated
+		case 1:
+			rematch.Player2 = seated
+		case 2:
+			rematch.Player3 = seated
+		}
+	}
+	rematch.State = WaitingForOpponent
+	if err := rematch.start(); err != nil {
+		return nil, err
+	}
+	return rematch, nil
+}
+
+// Points returns the points a participant scored in a finished game: 1 for a
+// win and a share of the point for draws and forfeits without a winner
+func (g *Game) Points(id ParticipantId) float64 {
+	o := g.Outcome
+	player := g.SeatedPlayer(id)
+	if o == nil || player == nil || o.Kind == Abandonment {
+		return 0
+	}
+	switch {
+	case o.Winner != nil:
+		if o.Winner == player {
+			return 1
+		}
+		return 0
+	case o.Loser == nil:
+		return 1 / float64(g.PlayerCount())
+	case o.Loser != player:
+		return 1 / float64(g.PlayerCount()-1)
+	}
+	return 0
+}
+
+// HeadToHead adds up the points a participant scored in this game and every
+// game it is a rematch of
+func (g *Game) HeadToHead(id ParticipantId) float64 {
+	points := 0.0
+	for game := g; game != nil; game = game.RematchOf {
+		points += game.Points(id)
+	}
+	return points
+}
+
+// FormatPoints writes points the way scores are usually written, e.g. "2½"
+func FormatPoints(points float64) string {
+	whole := int(points)
+	switch fraction := points - float64(whole); {
+	case fraction < 0.01:
+		return strconv.Itoa(whole)
+	case fraction > 0.49 && fraction < 0.51 && whole == 0:
+		return "½"
+	case fraction > 0.49 && fraction < 0.51:
+		return fmt.Sprintf("%d½", whole)
+	}
+	return strconv.FormatFloat(points, 'f', 2, 64)
+}
@@ -0,0 +1,261 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Rules decide how a game is played on its board. Game delegates everything
+// that differs between variants to the rules of its Variant
+type Rules interface {
+	// Piece returns the piece a player places unless they pick another one
+	Piece(player int) int
+	// LegalMoves returns every move the player to move can make
+	LegalMoves(g *Game) []Move
+	// Legal returns why a move cannot be played, nil if it can
+	Legal(g *Game, move Move) error
+	// Apply puts a legal move on the game's board
+	Apply(g *Game, move Move) error
+	// Outcome describes how the last move ended the game, nil while it goes
+	// on. The game fills in Outcome.Move
+	Outcome(g *Game) *Outcome
+	// SideToMove returns the player whose turn it is
+	SideToMove(g *Game) int
+}
+
+// classicRules are k in a row on a single board and the variants that only
+// change who places what and whether a line wins or loses
+type classicRules struct {
+	misere   bool // Completing a line loses
+	anyPiece bool // Players may place X or O
+	onlyX    bool // Both players place X
+}
+
+var (
+	ClassicRules Rules = classicRules{}
+	// Completing a line loses
+	MisereRules Rules = classicRules{misere: true}
+	// Either player may place X or O, completing a line of either wins
+	WildRules Rules = classicRules{anyPiece: true}
+	// Both players place X, completing a line loses
+	NotaktoRules Rules = classicRules{misere: true, onlyX: true}
+)
+
+func (r classicRules) Piece(player int) int {
+	if r.onlyX {
+		return 0b01
+	}
+	return player
+}
+
+func (r classicRules) pieces(player int) []int {
+	if r.anyPiece {
+		return []int{0b01, 0b10}
+	}
+	return []int{r.Piece(player)}
+}
+
+func (r classicRules) LegalMoves(g *Game) []Move {
+	player := r.SideToMove(g)
+	var moves []Move
+	for i := 0; i < g.Board.Size(); i++ {
+		if g.Board.GetCell(i) != 0b00 {
+			continue
+		}
+		for _, piece := range r.pieces(player) {
+			moves = append(moves, Move{Player: player, Cell: i, Piece: piece})
+		}
+	}
+	return moves
+}
+
+func (r classicRules) Legal(g *Game, move Move) error {
+	if err := checkCell(&g.Board, move.Cell); err != nil {
+		return err
+	}
+	for _, piece := range r.pieces(move.Player) {
+		if piece == move.Piece {
+			return nil
+		}
+	}
+	return fmt.Errorf("You cannot place %s", PlayerSymbol(move.Piece))
+}
+
+func (r classicRules) Apply(g *Game, move Move) error {
+	return g.Board.setCell(move.Cell, move.Piece)
+}
+
+func (r classicRules) Outcome(g *Game) *Outcome {
+	last := g.LastMove()
+	if last == nil {
+		return nil
+	}
+	if line := g.Board.WinningLineThrough(last.Cell); line != nil {
+		return lineOutcome(g, last, line, r.misere)
+	}
+	if g.Board.Full() {
+		return &Outcome{Kind: Draw, Reason: "The board is full"}
+	}
+	if g.EarlyDraws && r.drawn(g, r.SideToMove(g)) {
+		return &Outcome{Kind: Draw, Reason: "No winning line is possible anymore"}
+	}
+	return nil
+}
+
+// drawn reports whether no line can be completed anymore
+func (r classicRules) drawn(g *Game, next int) bool {
+	switch {
+	case r.onlyX:
+		// Every line stays open when nobody places O
+		return false
+	case r.anyPiece:
+		return g.Board.Blocked()
+	}
+	return g.Board.DrawnFor(next, g.PlayerCount())
+}
+
+func (r classicRules) SideToMove(g *Game) int {
+	return alternate(g)
+}
+
+// ultimateRules play on the small boards of an UltimateBoard
+type ultimateRules struct{}
+
+var UltimateRules Rules = ultimateRules{}
+
+func (ultimateRules) Piece(player int) int {
+	return player
+}
+
+func (r ultimateRules) LegalMoves(g *Game) []Move {
+	u := g.UltimateBoard()
+	player := r.SideToMove(g)
+	var moves []Move
+	for _, cell := range u.LegalMoves() {
+		moves = append(moves, Move{Player: player, Cell: cell, Piece: player})
+	}
+	return moves
+}
+
+func (ultimateRules) Legal(g *Game, move Move) error {
+	if move.Piece != move.Player {
+		return fmt.Errorf("You cannot place %s", PlayerSymbol(move.Piece))
+	}
+	return g.UltimateBoard().CanPlay(move.Cell)
+}
+
+func (ultimateRules) Apply(g *Game, move Move) error {
+	return g.Board.setCell(move.Cell, move.Piece)
+}
+
+func (ultimateRules) Outcome(g *Game) *Outcome {
+	last := g.LastMove()
+	if last == nil {
+		return nil
+	}
+	u := g.UltimateBoard()
+	if line := u.WinningLine(); line != nil {
+		return &Outcome{
+			Kind:   Win,
+			Winner: g.seat(last.Player),
+			Reason: fmt.Sprintf("%d boards in a row", ultimateSide),
+			Line:   line,
+		}
+	}
+	if u.Finished() {
+		return &Outcome{Kind: Draw, Reason: "No board is left to play on"}
+	}
+	return nil
+}
+
+func (ultimateRules) SideToMove(g *Game) int {
+	return alternate(g)
+}
+
+// qubicRules play on the lines of a QubicBoard
+type qubicRules struct{}
+
+var QubicRules Rules = qubicRules{}
+
+func (qubicRules) Piece(player int) int {
+	return player
+}
+
+func (r qubicRules) LegalMoves(g *Game) []Move {
+	return ClassicRules.LegalMoves(g)
+}
+
+func (qubicRules) Legal(g *Game, move Move) error {
+	return ClassicRules.Legal(g, move)
+}
+
+func (qubicRules) Apply(g *Game, move Move) error {
+	return g.Board.setCell(move.Cell, move.Piece)
+}
+
+func (qubicRules) Outcome(g *Game) *Outcome {
+	last := g.LastMove()
+	if last == nil {
+		return nil
+	}
+	q := g.QubicBoard()
+	if line := q.WinningLineThrough(last.Cell); line != nil {
+		return lineOutcome(g, last, line, false)
+	}
+	if q.Full() {
+		return &Outcome{Kind: Draw, Reason: "The board is full"}
+	}
+	if g.EarlyDraws && q.Drawn() {
+		return &Outcome{Kind: Draw, Reason: "No winning line is possible anymore"}
+	}
+	return nil
+}
+
+func (qubicRules) SideToMove(g *Game) int {
+	return alternate(g)
+}
+
+// alternate is the side to move of every variant so far: X moves first and
+// then the players take turns
+func alternate(g *Game) int {
+	if last := g.LastMove(); last != nil {
+		return g.playerAfter(last.Player)
+	}
+	return g.firstPlayer()
+}
+
+func checkCell(b *Board, index int) error {
+	if index < 0 || index >= b.Size() {
+		return errors.New("Invalid cell")
+	}
+	if b.GetCell(index) != 0b00 {
+		return errors.New("Cell not empty")
+	}
+	return nil
+}
+
+// lineOutcome is the outcome of a move that completed a line, in misère
+// variants the player that completed it loses
+func lineOutcome(g *Game, move *Move, line *Line, misere bool) *Outcome {
+	if !misere {
+		return &Outcome{
+			Kind:   Win,
+			Winner: g.seat(move.Player),
+			Reason: fmt.Sprintf("%d in a row", len(line.Cells)),
+			Line:   line,
+		}
+	}
+
+	loser := g.seat(move.Player)
+	reason := fmt.Sprintf("%d in a row", len(line.Cells))
+	if loser != nil {
+		reason = loser.Name + " completed " + reason
+	}
+	return &Outcome{
+		Kind:   Win,
+		Winner: g.seat(Opponent(move.Player)),
+		Loser:  loser,
+		Reason: reason,
+		Line:   line,
+	}
+}
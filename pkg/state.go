@@ -0,0 +1,108 @@
+package tictactoe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GameState is the phase of a game's lifecycle
+type GameState int
+
+const (
+	// Nobody has taken a seat yet
+	Lobby GameState = iota
+	// The first seat is taken
+	WaitingForOpponent
+	InProgress
+	// Play is on hold, e.g. while a player is disconnected
+	Paused
+	// The game ended with an outcome other than abandonment
+	Finished
+	Abandoned
+)
+
+func (s GameState) String() string {
+	switch s {
+	case Lobby:
+		return "Lobby"
+	case WaitingForOpponent:
+		return "Waiting for opponent"
+	case InProgress:
+		return "In progress"
+	case Paused:
+		return "Paused"
+	case Finished:
+		return "Finished"
+	case Abandoned:
+		return "Abandoned"
+	}
+	return "Unknown"
+}
+
+// States every state can move to, finished and abandoned games stay that way
+var gameStateTransitions = map[GameState][]GameState{
+	Lobby:              {WaitingForOpponent, Abandoned},
+	WaitingForOpponent: {InProgress, Abandoned},
+	InProgress:         {Paused, Finished, Abandoned},
+	Paused:             {InProgress, Finished, Abandoned},
+}
+
+// CanTransitionTo reports whether a game in this state may move to next
+func (s GameState) CanTransitionTo(next GameState) bool {
+	for _, state := range gameStateTransitions[s] {
+		if state == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Terminal reports whether the game can no longer change state
+func (s GameState) Terminal() bool {
+	return len(gameStateTransitions[s]) == 0
+}
+
+func (g *Game) transition(next GameState) error {
+	if !g.State.CanTransitionTo(next) {
+		return fmt.Errorf("Cannot go from %s to %s", strings.ToLower(g.State.String()), strings.ToLower(next.String()))
+	}
+	// Clocks only run while the game is being played
+	g.stopClock()
+	g.State = next
+	if next == InProgress {
+		g.startClock()
+	}
+	return nil
+}
+
+// Pause puts a game in progress on hold, no moves can be played until it is
+// resumed
+func (g *Game) Pause() error {
+	return g.transition(Paused)
+}
+
+// PauseFor holds the game while a player is disconnected. Untimed games are
+// paused. Timed games are only paused while it is the player's turn, and
+// their clock keeps running: a player can't stop the clock by leaving, and
+// the others can still move while they are away
+func (g *Game) PauseFor(player *Participant) error {
+	if g.Clocks == nil {
+		return g.Pause()
+	}
+	if player != g.CurrentPlayer {
+		return nil
+	}
+	if err := g.Pause(); err != nil {
+		return err
+	}
+	g.startClock()
+	return nil
+}
+
+// Resume continues a paused game
+func (g *Game) Resume() error {
+	if g.State != Paused {
+		return fmt.Errorf("Cannot resume a game that is %s", strings.ToLower(g.State.String()))
+	}
+	return g.transition(InProgress)
+}
@@ -0,0 +1,227 @@
+package tictactoe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// Symmetry is a rotation or reflection of a board
+type Symmetry int
+
+const (
+	Identity Symmetry = iota
+	Rotate90          // Clockwise
+	Rotate180
+	Rotate270
+	FlipHorizontal // Mirror left to right
+	FlipVertical   // Mirror top to bottom
+	FlipDiagonal   // Transpose along the top left to bottom right diagonal
+	FlipAntiDiagonal
+)
+
+var allSymmetries = []Symmetry{
+	Identity, Rotate90, Rotate180, Rotate270,
+	FlipHorizontal, FlipVertical, FlipDiagonal, FlipAntiDiagonal,
+}
+
+// Only these keep the shape of a board that isn't square
+var rectangleSymmetries = []Symmetry{Identity, Rotate180, FlipHorizontal, FlipVertical}
+
+func (s Symmetry) String() string {
+	switch s {
+	case Identity:
+		return "identity"
+	case Rotate90:
+		return "rotate 90"
+	case Rotate180:
+		return "rotate 180"
+	case Rotate270:
+		return "rotate 270"
+	case FlipHorizontal:
+		return "flip horizontal"
+	case FlipVertical:
+		return "flip vertical"
+	case FlipDiagonal:
+		return "flip diagonal"
+	case FlipAntiDiagonal:
+		return "flip anti-diagonal"
+	}
+	return "unknown"
+}
+
+// Inverse returns the symmetry that undoes s
+func (s Symmetry) Inverse() Symmetry {
+	switch s {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	}
+	return s
+}
+
+// Symmetries returns the rotations and reflections that map the board onto
+// a board of the same shape
+func (b *Board) Symmetries() []Symmetry {
+	if b.width == b.height {
+		return allSymmetries
+	}
+	return rectangleSymmetries
+}
+
+var (
+	permutationsMu sync.Mutex
+	permutations   = map[[3]int][]int{}
+)
+
+// permutation returns where each cell of a width x height board ends up under
+// the symmetry
+func permutation(width int, height int, s Symmetry) []int {
+	key := [3]int{width, height, int(s)}
+	permutationsMu.Lock()
+	defer permutationsMu.Unlock()
+	if perm, exists := permutations[key]; exists {
+		return perm
+	}
+
+	perm := make([]int, width*height)
+	last := width - 1
+	for i := range perm {
+		r, c := i/width, i%width
+		switch s {
+		case Identity:
+		case Rotate90:
+			r, c = c, last-r
+		case Rotate180:
+			r, c = height-1-r, last-c
+		case Rotate270:
+			r, c = last-c, r
+		case FlipHorizontal:
+			c = last - c
+		case FlipVertical:
+			r = height - 1 - r
+		case FlipDiagonal:
+			r, c = c, r
+		case FlipAntiDiagonal:
+			r, c = last-c, last-r
+		}
+		perm[i] = r*width + c
+	}
+	permutations[key] = perm
+	return perm
+}
+
+// MapCell returns the index a cell moves to when the symmetry is applied
+func (b *Board) MapCell(s Symmetry, index int) int {
+	return permutation(b.width, b.height, s)[index]
+}
+
+// Transform returns a copy of the board with the symmetry applied. Symmetries
+// that would change the shape of a rectangular board are not supported
+func (b *Board) Transform(s Symmetry) (Board, error) {
+	if b.width != b.height && (s == Rotate90 || s == Rotate270 || s == FlipDiagonal || s == FlipAntiDiagonal) {
+		return Board{}, fmt.Errorf("cannot %s a %dx%d board", s, b.width, b.height)
+	}
+	return b.transform(s), nil
+}
+
+func (b *Board) transform(s Symmetry) Board {
+	if s == Identity {
+		return *b
+	}
+	perm := permutation(b.width, b.height, s)
+	result := b.empty()
+	for p := range b.players {
+		for i, to := range perm {
+			if b.players[p].has(i) {
+				result.players[p].set(to)
+			}
+		}
+	}
+	return result
+}
+
+// Rotate returns the board rotated 90 degrees clockwise
+func (b *Board) Rotate() (Board, error) {
+	return b.Transform(Rotate90)
+}
+
+// Reflect returns the board mirrored left to right
+func (b *Board) Reflect() Board {
+	return b.transform(FlipHorizontal)
+}
+
+func (b *Board) less(other *Board) bool {
+	for p := range b.players {
+		for i := len(b.players[p]) - 1; i >= 0; i-- {
+			if b.players[p][i] != other.players[p][i] {
+				return b.players[p][i] < other.players[p][i]
+			}
+		}
+	}
+	return false
+}
+
+// Canonical returns the smallest of the board's symmetric variants, which is
+// the same for every equivalent position, and the symmetry that produced it
+func (b *Board) Canonical() (Board, Symmetry) {
+	canonical, symmetry := *b, Identity
+	for _, s := range b.Symmetries()[1:] {
+		variant := b.transform(s)
+		if variant.less(&canonical) {
+			canonical, symmetry = variant, s
+		}
+	}
+	return canonical, symmetry
+}
+
+// Key is a stable, human readable identifier of the exact position, e.g.
+// "3x3/3:X.O/.X./..O"
+func (b *Board) Key() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%dx%d/%d:", b.width, b.height, b.winLength)
+	for i := 0; i < b.Size(); i++ {
+		if i > 0 && i%b.width == 0 {
+			sb.WriteByte('/')
+		}
+		switch s := b.Symbol(uint(i)); s {
+		case "":
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+// CanonicalKey is the Key of the canonical form, shared by equivalent positions
+func (b *Board) CanonicalKey() string {
+	canonical, _ := b.Canonical()
+	return canonical.Key()
+}
+
+// Hash is a stable 64 bit FNV-1a hash of the board's size and cell values
+func (b *Board) Hash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, n := range []int{b.width, b.height, b.winLength} {
+		binary.LittleEndian.PutUint64(buf[:], uint64(n))
+		h.Write(buf[:])
+	}
+	cells := make([]byte, b.Size())
+	for i := range cells {
+		cells[i] = byte(b.GetCell(i))
+	}
+	h.Write(cells)
+	return h.Sum64()
+}
+
+// CanonicalHash is the Hash of the canonical form, shared by equivalent
+// positions
+func (b *Board) CanonicalHash() uint64 {
+	canonical, _ := b.Canonical()
+	return canonical.Hash()
+}
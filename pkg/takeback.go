@@ -0,0 +1,110 @@
+package tictactoe
+
+import "errors"
+
+// Casual reports whether the game is played just for fun: no clocks and not
+// part of a match. Only casual games can allow unlimited undo
+func (g *Game) Casual() bool {
+	return g.Clocks == nil && g.Match == nil
+}
+
+// Takebacks reports whether players can take moves back. Timed games don't
+// allow it, the time spent on the moves would stay off the clocks
+func (g *Game) Takebacks() bool {
+	return g.Clocks == nil
+}
+
+// RequestTakeback asks the other players to take back the player's last move
+// and every move played after it. Games with unlimited undo take it back
+// right away and return true
+func (g *Game) RequestTakeback(player *Participant) (bool, error) {
+	if err := g.checkTakeback(player); err != nil {
+		return false, err
+	}
+	if g.TakebackRequest != nil {
+		return false, errors.New("A takeback has already been requested")
+	}
+	if g.UnlimitedUndo {
+		return true, g.takeBack(player)
+	}
+	g.TakebackRequest = player
+	return false, nil
+}
+
+// AcceptTakeback approves the takeback another player asked for
+func (g *Game) AcceptTakeback(player *Participant) error {
+	requester := g.TakebackRequest
+	if requester == nil {
+		return errors.New("No takeback was requested")
+	}
+	if requester == player {
+		return errors.New("You cannot approve your own takeback")
+	}
+	if err := g.checkTakeback(requester); err != nil {
+		return err
+	}
+	if g.PlayerValue(player) == 0 {
+		return errors.New("Not a player in this game")
+	}
+	return g.takeBack(requester)
+}
+
+// DeclineTakeback turns down the takeback another player asked for
+func (g *Game) DeclineTakeback(player *Participant) error {
+	if g.TakebackRequest == nil {
+		return errors.New("No takeback was requested")
+	}
+	if g.TakebackRequest == player {
+		return errors.New("You cannot decline your own takeback")
+	}
+	if g.PlayerValue(player) == 0 {
+		return errors.New("Not a player in this game")
+	}
+	g.TakebackRequest = nil
+	return nil
+}
+
+// lastMoveOf returns the ply of the player's last move, 0 if they did not
+// play yet
+func (g *Game) lastMoveOf(player *Participant) int {
+	value := g.PlayerValue(player)
+	for i := len(g.Moves) - 1; i >= 0; i-- {
+		if g.Moves[i].Player == value {
+			return g.Moves[i].Ply
+		}
+	}
+	return 0
+}
+
+func (g *Game) checkTakeback(player *Participant) error {
+	if !g.Takebacks() {
+		return errors.New("Moves can't be taken back in timed games")
+	}
+	if g.State != InProgress {
+		return errors.New("Moves can only be taken back while the game is being played")
+	}
+	if g.PlayerValue(player) == 0 {
+		return errors.New("Not a player in this game")
+	}
+	if g.lastMoveOf(player) <= g.ForkPly {
+		// Moves copied from the game this one continues from stay
+		return errors.New("You have no move to take back")
+	}
+	return nil
+}
+
+// takeBack rolls the game back to the board before the player's last move,
+// it's their turn again
+func (g *Game) takeBack(player *Participant) error {
+	ply := g.lastMoveOf(player) - 1
+	board, err := g.BoardAt(ply)
+	if err != nil {
+		return err
+	}
+	g.Board = board
+	g.Moves = g.Moves[:ply]
+	g.CurrentPlayer = g.seat(g.Rules().SideToMove(g))
+	g.TakebackRequest = nil
+	g.DrawOffers = nil
+	return nil
+}
@@ -0,0 +1,220 @@
+package tictactoe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Side of the small boards and of the meta-board of ultimate tic-tac-toe
+const ultimateSide = 3
+
+// Number of cells of an ultimate board (9x9)
+const ultimateCells = ultimateSide * ultimateSide * ultimateSide * ultimateSide
+
+// UltimateBoard is ultimate tic-tac-toe: nine small boards laid out as a 3x3
+// board. Winning a small board claims its cell on the meta-board and three
+// claimed boards in a row win the game. The cell played decides the small
+// board the opponent has to play in next.
+//
+// Cells are numbered as on the flat 9x9 board, see Locate
+type UltimateBoard struct {
+	Boards [ultimateSide * ultimateSide]Board // Small boards, row by row
+	Meta   Board                              // Cell i belongs to the winner of Boards[i]
+	Active int                                // Small board the next move has to be played in, -1 for any
+}
+
+func NewUltimateBoard() *UltimateBoard {
+	u := &UltimateBoard{Active: -1}
+	for i := range u.Boards {
+		u.Boards[i] = *NewBoard()
+	}
+	u.Meta = *NewBoard()
+	return u
+}
+
+// newUltimateFlatBoard returns the 9x9 board an ultimate game keeps all of its
+// pieces on
+func newUltimateFlatBoard() *Board {
+	side := ultimateSide * ultimateSide
+	b, _ := NewBoardWithSize(side, side, ultimateSide)
+	return b
+}
+
+// UltimateBoardFrom rebuilds the small boards from the pieces on a flat 9x9
+// board, last is the move that was played last (nil if none)
+func UltimateBoardFrom(board *Board, last *Move) (*UltimateBoard, error) {
+	if board.Size() != ultimateCells {
+		return nil, fmt.Errorf("an ultimate board has %d cells, not %d", ultimateCells, board.Size())
+	}
+	u := NewUltimateBoard()
+	for i := 0; i < ultimateCells; i++ {
+		if player := board.GetCell(i); player != 0b00 {
+			sub, cell := u.Locate(i)
+			u.Boards[sub].setCell(cell, player)
+		}
+	}
+	for sub := range u.Boards {
+		if line := u.Boards[sub].WinningLine(); line != nil {
+			u.Meta.setCell(sub, u.Boards[sub].GetCell(line.Cells[0]))
+		}
+	}
+	if last != nil {
+		u.activate(last.Cell)
+	}
+	return u, nil
+}
+
+// Locate returns the small board a cell of the flat 9x9 board belongs to and
+// its index on that board
+func (u *UltimateBoard) Locate(index int) (sub int, cell int) {
+	side := ultimateSide * ultimateSide
+	row, col := index/side, index%side
+	sub = (row/ultimateSide)*ultimateSide + col/ultimateSide
+	cell = (row%ultimateSide)*ultimateSide + col%ultimateSide
+	return sub, cell
+}
+
+// Index is the inverse of Locate
+func (u *UltimateBoard) Index(sub int, cell int) int {
+	row := (sub/ultimateSide)*ultimateSide + cell/ultimateSide
+	col := (sub%ultimateSide)*ultimateSide + cell%ultimateSide
+	return row*ultimateSide*ultimateSide + col
+}
+
+// Closed reports whether a small board has been won or filled up
+func (u *UltimateBoard) Closed(sub int) bool {
+	return u.Meta.GetCell(sub) != 0b00 || u.Boards[sub].Full()
+}
+
+// Playable reports whether the next move may be played on a small board
+func (u *UltimateBoard) Playable(sub int) bool {
+	return !u.Closed(sub) && (u.Active < 0 || u.Active == sub)
+}
+
+// CanPlay returns why a cell cannot be played, nil if it can
+func (u *UltimateBoard) CanPlay(index int) error {
+	if index < 0 || index >= ultimateCells {
+		return errors.New("Invalid cell")
+	}
+	sub, cell := u.Locate(index)
+	if u.Boards[sub].GetCell(cell) != 0b00 {
+		return errors.New("Cell not empty")
+	}
+	if !u.Playable(sub) {
+		return fmt.Errorf("You have to play on board %d", u.Active+1)
+	}
+	return nil
+}
+
+// Play plays a move and returns the line of small boards it won the game
+// with, if any
+func (u *UltimateBoard) Play(index int, player int) (*Line, error) {
+	if err := u.CanPlay(index); err != nil {
+		return nil, err
+	}
+	sub, cell := u.Locate(index)
+	if err := u.Boards[sub].setCell(cell, player); err != nil {
+		return nil, err
+	}
+
+	var line *Line
+	if u.Boards[sub].WinningLineThrough(cell) != nil {
+		u.Meta.setCell(sub, player)
+		line = u.Meta.WinningLineThrough(sub)
+	}
+	u.activate(index)
+	return line, nil
+}
+
+// activate sends the next move to the small board matching the cell just
+// played, or anywhere if that board is closed
+func (u *UltimateBoard) activate(index int) {
+	_, cell := u.Locate(index)
+	u.Active = cell
+	if u.Closed(cell) {
+		u.Active = -1
+	}
+}
+
+// LegalMoves returns every cell of the flat board the next move can go to
+func (u *UltimateBoard) LegalMoves() []int {
+	var moves []int
+	for sub := range u.Boards {
+		if !u.Playable(sub) {
+			continue
+		}
+		for cell := 0; cell < u.Boards[sub].Size(); cell++ {
+			if u.Boards[sub].GetCell(cell) == 0b00 {
+				moves = append(moves, u.Index(sub, cell))
+			}
+		}
+	}
+	return moves
+}
+
+// WinningLine returns the line of small boards that won the game, if any
+func (u *UltimateBoard) WinningLine() *Line {
+	return u.Meta.WinningLine()
+}
+
+// Finished reports whether the game is won or no small board is left to play
+// in
+func (u *UltimateBoard) Finished() bool {
+	if u.WinningLine() != nil {
+		return true
+	}
+	for sub := range u.Boards {
+		if !u.Closed(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// SubCells returns the cells of a small board numbered as on the flat board
+func (u *UltimateBoard) SubCells(sub int) []*Cell {
+	cells := make([]*Cell, u.Boards[sub].Size())
+	for cell := range cells {
+		cells[cell] = &Cell{
+			Symbol: u.Boards[sub].Symbol(uint(cell)),
+			Index:  uint(u.Index(sub, cell)),
+		}
+	}
+	return cells
+}
+
+// Winner returns the symbol of the player that won a small board, if any
+func (u *UltimateBoard) Winner(sub int) string {
+	return u.Meta.Symbol(uint(sub))
+}
+
+// NewUltimateGame creates an ultimate tic-tac-toe game, its Board holds the
+// pieces of all small boards as a flat 9x9 board
+func NewUltimateGame(id GameId) *Game {
+	game := NewGameWithBoard(id, newUltimateFlatBoard())
+	game.Variant = Ultimate
+	return game
+}
+
+// UltimateBoard returns the current small boards of an ultimate game, nil for
+// other variants
+func (g *Game) UltimateBoard() *UltimateBoard {
+	u, _ := g.UltimateBoardAt(len(g.Moves))
+	return u
+}
+
+// UltimateBoardAt returns the small boards after the given number of moves
+func (g *Game) UltimateBoardAt(ply int) (*UltimateBoard, error) {
+	if g.Variant != Ultimate {
+		return nil, errors.New("Not an ultimate game")
+	}
+	board, err := g.BoardAt(ply)
+	if err != nil {
+		return nil, err
+	}
+	var last *Move
+	if ply > 0 {
+		last = &g.Moves[ply-1]
+	}
+	return UltimateBoardFrom(&board, last)
+}
@@ -0,0 +1,98 @@
+package tictactoe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant is the kind of game being played, it picks the board and the Rules
+type Variant int
+
+const (
+	// A single width x height board
+	Classic Variant = iota
+	// Nine 3x3 boards inside a 3x3 board, see UltimateBoard
+	Ultimate
+	// 4x4x4 cube, see QubicBoard
+	Qubic
+	// Completing a line loses
+	Misere
+	// Either player may place X or O
+	Wild
+	// Both players place X and completing a line loses
+	Notakto
+)
+
+var Variants = []Variant{Classic, Ultimate, Qubic, Misere, Wild, Notakto}
+
+func (v Variant) String() string {
+	switch v {
+	case Classic:
+		return "Classic"
+	case Ultimate:
+		return "Ultimate"
+	case Qubic:
+		return "Qubic"
+	case Misere:
+		return "Misère"
+	case Wild:
+		return "Wild"
+	case Notakto:
+		return "Notakto"
+	}
+	return "Unknown"
+}
+
+// Rules returns the rules games of the variant are played by
+func (v Variant) Rules() Rules {
+	switch v {
+	case Ultimate:
+		return UltimateRules
+	case Qubic:
+		return QubicRules
+	case Misere:
+		return MisereRules
+	case Wild:
+		return WildRules
+	case Notakto:
+		return NotaktoRules
+	}
+	return ClassicRules
+}
+
+// ParseVariant is the inverse of Variant.String, ignoring case
+func ParseVariant(s string) (Variant, error) {
+	for _, variant := range Variants {
+		if strings.EqualFold(variant.String(), s) {
+			return variant, nil
+		}
+	}
+	return Classic, fmt.Errorf("unknown variant %q", s)
+}
+
+// board returns the flat board a variant is always played on, nil when the
+// board size can be picked
+func (v Variant) board() *Board {
+	switch v {
+	case Ultimate:
+		return newUltimateFlatBoard()
+	case Qubic:
+		return newQubicFlatBoard()
+	}
+	return nil
+}
+
+// checkBoard makes sure a board has the size the variant is played on
+func (v Variant) checkBoard(b *Board) error {
+	if v < Classic || int(v) >= len(Variants) {
+		return fmt.Errorf("unknown variant %d", v)
+	}
+	fixed := v.board()
+	if fixed == nil {
+		return nil
+	}
+	if b.Width() != fixed.Width() || b.Height() != fixed.Height() || b.WinLength() != fixed.WinLength() {
+		return fmt.Errorf("a %s game is played on a %dx%d board with %d in a row", v, fixed.Width(), fixed.Height(), fixed.WinLength())
+	}
+	return nil
+}